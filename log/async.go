@@ -0,0 +1,260 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 队列满时阻塞等待，不丢日志
+	OverflowDropNewest                       // 队列满时丢弃新写入的日志
+	OverflowDropOldest                       // 队列满时丢弃队列中最早的日志
+)
+
+type AsyncConfig struct {
+	QueueSize     int            // 队列中最多缓存的日志条数
+	Overflow      OverflowPolicy // 队列满时的处理方式
+	FlushInterval time.Duration  // 定期将缓冲写入底层 writer 的间隔，<= 0 时使用默认的 1s
+	BufferSize    int            // 写入底层 writer 的缓冲区大小，单位 字节
+}
+
+type AsyncOption func(*AsyncConfig)
+
+func AsyncWithQueueSize(n int) AsyncOption {
+	return func(config *AsyncConfig) {
+		config.QueueSize = n
+	}
+}
+
+func AsyncWithOverflow(p OverflowPolicy) AsyncOption {
+	return func(config *AsyncConfig) {
+		config.Overflow = p
+	}
+}
+
+func AsyncWithFlushInterval(d time.Duration) AsyncOption {
+	return func(config *AsyncConfig) {
+		config.FlushInterval = d
+	}
+}
+
+func AsyncWithBufferSize(size int) AsyncOption {
+	return func(config *AsyncConfig) {
+		config.BufferSize = size
+	}
+}
+
+// AsyncWriter 异步写入的 writer，日志先进入有界队列，由后台协程批量写入底层 writer
+//
+//	Sync 会等待队列中的日志全部写入底层 writer，log.Sync 以及 Panic/Fatal 级别的日志都会触发 Sync
+//	程序退出前需要调用 log.Sync 或 Close，否则队列和缓冲区中的日志会丢失
+type AsyncWriter struct {
+	w        io.Writer
+	buf      *bufio.Writer
+	overflow OverflowPolicy
+	dropped  uint64
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	queue   [][]byte // 环形队列
+	head    int
+	count   int
+	closed  bool
+	err     error // 写入底层 writer 的第一个错误
+
+	closeErr error // Close 时 Sync 的结果
+
+	wake    chan struct{}
+	flushes chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewAsyncWriter 返回异步写入 w 的 writer，可以用于 ConfigWithWriters 和 NewSink
+//
+//	eg: NewAsyncWriter(NewWriterWithSize("./app.log"), AsyncWithOverflow(OverflowDropOldest))
+func NewAsyncWriter(w io.Writer, opts ...AsyncOption) *AsyncWriter {
+	config := &AsyncConfig{
+		QueueSize:     8192,
+		Overflow:      OverflowBlock,
+		FlushInterval: time.Second,
+		BufferSize:    256 << 10,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.QueueSize < 1 {
+		config.QueueSize = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+
+	a := &AsyncWriter{
+		w:        w,
+		buf:      bufio.NewWriterSize(w, config.BufferSize),
+		overflow: config.Overflow,
+		queue:    make([][]byte, config.QueueSize),
+		wake:     make(chan struct{}, 1),
+		flushes:  make(chan chan error),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	a.notFull = sync.NewCond(&a.mu)
+	go a.run(config.FlushInterval)
+	return a
+}
+
+// Write 将 p 的副本放入队列，Close 之后直接写入底层 writer
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	entry := append([]byte(nil), p...)
+
+	a.mu.Lock()
+	for !a.closed && a.count == len(a.queue) {
+		if a.overflow == OverflowDropNewest {
+			a.mu.Unlock()
+			atomic.AddUint64(&a.dropped, 1)
+			return len(p), nil
+		}
+		if a.overflow == OverflowDropOldest {
+			a.pop()
+			atomic.AddUint64(&a.dropped, 1)
+			break
+		}
+		a.notFull.Wait()
+	}
+	if a.closed {
+		a.mu.Unlock()
+		return a.w.Write(p)
+	}
+	a.queue[(a.head+a.count)%len(a.queue)] = entry
+	a.count++
+	a.mu.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Sync 等待队列中的日志全部写入底层 writer，底层 writer 支持 Sync 时同时调用
+func (a *AsyncWriter) Sync() error {
+	ack := make(chan error, 1)
+	select {
+	case a.flushes <- ack:
+		return <-ack
+	case <-a.stopped:
+		return a.syncUnderlying()
+	}
+}
+
+// Close 写入剩余的日志并停止后台协程，之后的写入直接写入底层 writer
+func (a *AsyncWriter) Close() error {
+	a.once.Do(func() {
+		close(a.done)
+	})
+	<-a.stopped
+	return a.closeErr
+}
+
+// Dropped 返回队列满时被丢弃的日志条数
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+func (a *AsyncWriter) run(interval time.Duration) {
+	defer close(a.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.wake:
+			a.drain()
+		case <-ticker.C:
+			a.drain()
+			a.flush()
+		case ack := <-a.flushes:
+			a.drain()
+			a.flush()
+			ack <- a.syncUnderlying()
+		case <-a.done:
+			a.mu.Lock()
+			a.closed = true
+			a.notFull.Broadcast()
+			a.mu.Unlock()
+			a.drain()
+			a.flush()
+			a.closeErr = a.syncUnderlying()
+			return
+		}
+	}
+}
+
+// drain 将队列中的日志全部写入缓冲区
+func (a *AsyncWriter) drain() {
+	for {
+		a.mu.Lock()
+		if a.count == 0 {
+			a.mu.Unlock()
+			return
+		}
+		batch := make([][]byte, 0, a.count)
+		for a.count > 0 {
+			batch = append(batch, a.pop())
+		}
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		for _, entry := range batch {
+			if _, err := a.buf.Write(entry); err != nil {
+				a.setErr(err)
+			}
+		}
+	}
+}
+
+// pop 取出队首的日志，调用方需要持有锁
+func (a *AsyncWriter) pop() []byte {
+	entry := a.queue[a.head]
+	a.queue[a.head] = nil
+	a.head = (a.head + 1) % len(a.queue)
+	a.count--
+	return entry
+}
+
+func (a *AsyncWriter) flush() {
+	if err := a.buf.Flush(); err != nil {
+		a.setErr(err)
+		// 丢弃写入失败的缓冲，避免之后一直失败
+		a.buf.Reset(a.w)
+	}
+}
+
+func (a *AsyncWriter) syncUnderlying() error {
+	if s, ok := a.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return err
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+func (a *AsyncWriter) setErr(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = err
+	}
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer 可以并发读写的 buffer
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// blockingWriter 第一次写入后阻塞，直到 release 被关闭
+type blockingWriter struct {
+	lockedBuffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	return w.lockedBuffer.Write(p)
+}
+
+// entry 生成超过 bufio 最小缓冲区的日志行，保证每行都直接写入底层 writer
+func entry(i int) []byte {
+	return []byte(fmt.Sprintf("entry-%d %s\n", i, strings.Repeat("x", 32)))
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   []int
+	}{
+		{OverflowDropNewest, []int{0, 1, 2}},
+		{OverflowDropOldest, []int{0, 3, 4}},
+	}
+	for _, tt := range tests {
+		w := newBlockingWriter()
+		a := NewAsyncWriter(w, AsyncWithQueueSize(2), AsyncWithOverflow(tt.policy), AsyncWithBufferSize(16))
+
+		a.Write(entry(0))
+		<-w.started
+		for i := 1; i < 5; i++ {
+			a.Write(entry(i))
+		}
+		if a.Dropped() != 2 {
+			t.Fatalf("policy %d: dropped %d, want 2", tt.policy, a.Dropped())
+		}
+		close(w.release)
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var want string
+		for _, i := range tt.want {
+			want += string(entry(i))
+		}
+		if got := w.String(); got != want {
+			t.Fatalf("policy %d: got %q, want %q", tt.policy, got, want)
+		}
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	w := newBlockingWriter()
+	a := NewAsyncWriter(w, AsyncWithQueueSize(1), AsyncWithBufferSize(16))
+
+	a.Write(entry(0))
+	<-w.started
+	a.Write(entry(1))
+
+	written := make(chan struct{})
+	go func() {
+		a.Write(entry(2))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("write did not block on full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	<-written
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.String(), string(entry(0))+string(entry(1))+string(entry(2)); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	a.Close()
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	var buf lockedBuffer
+	a := NewAsyncWriter(&buf, AsyncWithFlushInterval(20*time.Millisecond))
+	defer a.Close()
+
+	a.Write([]byte("periodic\n"))
+	deadline := time.Now().Add(time.Second)
+	for buf.String() != "periodic\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("not flushed, got %q", buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAsyncWriterZeroFlushInterval(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		var buf lockedBuffer
+		a := NewAsyncWriter(&buf, AsyncWithFlushInterval(d))
+		a.Write([]byte("line\n"))
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "line\n" {
+			t.Fatalf("interval %s: got %q", d, buf.String())
+		}
+	}
+}
+
+func TestAsyncWriterLogger(t *testing.T) {
+	var buf lockedBuffer
+	a := NewAsyncWriter(&buf, AsyncWithFlushInterval(time.Hour))
+	Init(ConfigWithWriters([]io.Writer{a}))
+	defer Init()
+	defer a.Close()
+
+	Info("buffered msg")
+	if strings.Contains(buf.String(), "buffered msg") {
+		t.Fatal("written before sync")
+	}
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "buffered msg") {
+		t.Fatalf("not flushed by Sync: %q", buf.String())
+	}
+
+	// Panic 级别的日志写入后会立即 Sync
+	func() {
+		defer func() { recover() }()
+		Panic("panic msg")
+	}()
+	if !strings.Contains(buf.String(), "panic msg") {
+		t.Fatalf("not flushed on panic: %q", buf.String())
+	}
+}
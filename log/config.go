@@ -0,0 +1,432 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 声明式的日志配置，可以从 YAML/JSON 文件和环境变量加载
+//
+//	eg:
+//	level: info
+//	encoder: json
+//	stacktraceLevel: error
+//	outputs:
+//	  - type: stdout
+//	    encoder: console
+//	  - type: file
+//	    path: ./logs/error.log
+//	    minLevel: error
+//	    rotation: {by: size, maxSize: 100, maxBackups: 10, maxAge: 720h}
+//	sampling:
+//	  - {levels: [debug, info], tick: 1s, first: 100, thereafter: 100}
+type Config struct {
+	Level           string         `json:"level" yaml:"level"`                     // debug/info/warn/error/dpanic/panic/fatal，默认 debug
+	Encoder         string         `json:"encoder" yaml:"encoder"`                 // console/json，默认 console
+	Caller          *bool          `json:"caller" yaml:"caller"`                   // 是否输出调用位置，默认输出
+	StacktraceLevel string         `json:"stacktraceLevel" yaml:"stacktraceLevel"` // 输出调用栈的最低级别，默认 fatal
+	Outputs         []OutputConfig `json:"outputs" yaml:"outputs"`                 // 为空时输出到标准输出
+	Sampling        []SamplingRule `json:"sampling" yaml:"sampling"`
+}
+
+// OutputConfig 一个输出，对应 ConfigWithSinks 中的一个 Sink
+type OutputConfig struct {
+	Type     string          `json:"type" yaml:"type"`         // stdout/stderr/file
+	Path     string          `json:"path" yaml:"path"`         // type 为 file 时的文件路径
+	Encoder  string          `json:"encoder" yaml:"encoder"`   // 默认与 Config.Encoder 相同
+	MinLevel string          `json:"minLevel" yaml:"minLevel"` // 默认 debug
+	MaxLevel string          `json:"maxLevel" yaml:"maxLevel"` // 默认 fatal
+	Rotation *RotationConfig `json:"rotation" yaml:"rotation"` // 不设置时不切割
+}
+
+// RotationConfig 文件切割配置，未设置的字段使用 NewWriterWithSize/NewWriterWithAge 的默认值
+type RotationConfig struct {
+	By           string `json:"by" yaml:"by"`                     // size/age
+	MaxSize      int    `json:"maxSize" yaml:"maxSize"`           // size，单位 MB
+	MaxBackups   int    `json:"maxBackups" yaml:"maxBackups"`     // size
+	MaxAge       string `json:"maxAge" yaml:"maxAge"`             // 保留时间，eg: "720h"，size 时按天向上取整
+	Compress     *bool  `json:"compress" yaml:"compress"`         // size
+	RotationTime string `json:"rotationTime" yaml:"rotationTime"` // age，eg: "24h"
+	Format       string `json:"format" yaml:"format"`             // age，切割后文件名中的日期格式
+}
+
+// SamplingRule 对应一次 ConfigWithSampling，不设置 levels 时作用于所有级别
+type SamplingRule struct {
+	Levels     []string `json:"levels" yaml:"levels"`
+	Tick       string   `json:"tick" yaml:"tick"` // eg: "1s"
+	First      int      `json:"first" yaml:"first"`
+	Thereafter int      `json:"thereafter" yaml:"thereafter"`
+}
+
+// LoadConfig 从文件加载配置，根据扩展名 .yaml/.yml/.json 选择格式，未知字段视为错误
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read log config err: %w", err)
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return ParseConfig(data, format)
+}
+
+// ParseConfig 解析 format 格式的配置，format 为 yaml/yml/json
+func ParseConfig(data []byte, format string) (*Config, error) {
+	c := &Config{}
+	switch format {
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("parse log config err: %w", err)
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return nil, fmt.Errorf("parse log config err: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported log config format %q", format)
+	}
+	return c, nil
+}
+
+// ApplyEnv 使用环境变量覆盖配置，prefix 为空时使用 LOG
+//
+//	<prefix>_LEVEL、<prefix>_ENCODER、<prefix>_CALLER、<prefix>_STACKTRACE_LEVEL
+//	<prefix>_OUTPUTS 逗号分隔的 stdout、stderr 或文件路径，替换全部输出
+//	<prefix>_SAMPLING_TICK、<prefix>_SAMPLING_FIRST、<prefix>_SAMPLING_THEREAFTER 替换全部采样规则，作用于所有级别
+func (c *Config) ApplyEnv(prefix string) error {
+	if prefix == "" {
+		prefix = "LOG"
+	}
+	env := func(name string) (string, bool) {
+		return os.LookupEnv(prefix + "_" + name)
+	}
+
+	if v, ok := env("LEVEL"); ok {
+		c.Level = v
+	}
+	if v, ok := env("ENCODER"); ok {
+		c.Encoder = v
+	}
+	if v, ok := env("CALLER"); ok {
+		caller, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s_CALLER: %w", prefix, err)
+		}
+		c.Caller = &caller
+	}
+	if v, ok := env("STACKTRACE_LEVEL"); ok {
+		c.StacktraceLevel = v
+	}
+	if v, ok := env("OUTPUTS"); ok {
+		c.Outputs = nil
+		for _, out := range strings.Split(v, ",") {
+			out = strings.TrimSpace(out)
+			switch out {
+			case "":
+			case "stdout", "stderr":
+				c.Outputs = append(c.Outputs, OutputConfig{Type: out})
+			default:
+				c.Outputs = append(c.Outputs, OutputConfig{Type: "file", Path: out})
+			}
+		}
+	}
+
+	tick, hasTick := env("SAMPLING_TICK")
+	first, hasFirst := env("SAMPLING_FIRST")
+	thereafter, hasThereafter := env("SAMPLING_THEREAFTER")
+	if hasTick || hasFirst || hasThereafter {
+		rule := SamplingRule{Tick: tick}
+		var err error
+		if hasFirst {
+			if rule.First, err = strconv.Atoi(first); err != nil {
+				return fmt.Errorf("%s_SAMPLING_FIRST: %w", prefix, err)
+			}
+		}
+		if hasThereafter {
+			if rule.Thereafter, err = strconv.Atoi(thereafter); err != nil {
+				return fmt.Errorf("%s_SAMPLING_THEREAFTER: %w", prefix, err)
+			}
+		}
+		c.Sampling = []SamplingRule{rule}
+	}
+	return nil
+}
+
+// Validate 检查配置，不会创建文件
+func (c *Config) Validate() error {
+	_, err := c.build(false)
+	return err
+}
+
+// Options 检查配置并转换为 Option，配置正确时才会创建输出的文件
+func (c *Config) Options() ([]Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c.build(true)
+}
+
+// NewLoggerWithConfig 根据配置创建 logger，配置错误时返回错误
+func NewLoggerWithConfig(c *Config) (*zap.SugaredLogger, error) {
+	opts, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(opts...), nil
+}
+
+// InitWithConfig 根据配置初始化全局日志，配置错误时返回错误且不修改全局日志
+//
+//	eg:
+//	c, err := log.LoadConfig("./log.yaml")
+//	if err != nil { ... }
+//	if err = c.ApplyEnv("LOG"); err != nil { ... }
+//	if err = log.InitWithConfig(c); err != nil { ... }
+func InitWithConfig(c *Config) error {
+	opts, err := c.Options()
+	if err != nil {
+		return err
+	}
+	Init(opts...)
+	return nil
+}
+
+func (c *Config) build(open bool) ([]Option, error) {
+	var opts []Option
+
+	l, err := parseLevel(c.Level, zapcore.DebugLevel)
+	if err != nil {
+		return nil, fmt.Errorf("level: %w", err)
+	}
+	opts = append(opts, ConfigWithLevel(l))
+
+	encoder, err := parseEncoder(c.Encoder, EncoderConsole)
+	if err != nil {
+		return nil, fmt.Errorf("encoder: %w", err)
+	}
+	opts = append(opts, ConfigWithEncoder(encoder))
+
+	if c.Caller != nil && !*c.Caller {
+		opts = append(opts, ConfigWithoutCaller())
+	}
+
+	stacktrace, err := parseLevel(c.StacktraceLevel, zapcore.FatalLevel)
+	if err != nil {
+		return nil, fmt.Errorf("stacktraceLevel: %w", err)
+	}
+	opts = append(opts, ConfigWithStacktraceLevel(stacktrace))
+
+	for i, rule := range c.Sampling {
+		opt, err := rule.option()
+		if err != nil {
+			return nil, fmt.Errorf("sampling[%d]: %w", i, err)
+		}
+		opts = append(opts, opt)
+	}
+
+	// 输出放在最后处理，出错时关闭已经打开的文件
+	var sinks []Sink
+	var files []io.Closer
+	for i, out := range c.Outputs {
+		sink, err := out.sink(open)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+		if closer, ok := sink.writer.(io.Closer); ok && out.Type == "file" {
+			files = append(files, closer)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) > 0 {
+		opts = append(opts, ConfigWithSinks(sinks...))
+	}
+	return opts, nil
+}
+
+func (o OutputConfig) sink(open bool) (Sink, error) {
+	var sinkOpts []SinkOption
+	if o.Encoder != "" {
+		encoder, err := parseEncoder(o.Encoder, "")
+		if err != nil {
+			return Sink{}, fmt.Errorf("encoder: %w", err)
+		}
+		sinkOpts = append(sinkOpts, SinkWithEncoder(encoder))
+	}
+	minLevel, err := parseLevel(o.MinLevel, zapcore.DebugLevel)
+	if err != nil {
+		return Sink{}, fmt.Errorf("minLevel: %w", err)
+	}
+	maxLevel, err := parseLevel(o.MaxLevel, zapcore.FatalLevel)
+	if err != nil {
+		return Sink{}, fmt.Errorf("maxLevel: %w", err)
+	}
+	if minLevel > maxLevel {
+		return Sink{}, fmt.Errorf("minLevel %s is above maxLevel %s", minLevel, maxLevel)
+	}
+	sinkOpts = append(sinkOpts, SinkWithMinLevel(minLevel), SinkWithMaxLevel(maxLevel))
+
+	var w io.Writer
+	switch o.Type {
+	case "stdout", "":
+		if o.Path != "" || o.Rotation != nil {
+			return Sink{}, fmt.Errorf("path and rotation require type file")
+		}
+		w = os.Stdout
+	case "stderr":
+		if o.Path != "" || o.Rotation != nil {
+			return Sink{}, fmt.Errorf("path and rotation require type file")
+		}
+		w = os.Stderr
+	case "file":
+		if o.Path == "" {
+			return Sink{}, fmt.Errorf("path is required for type file")
+		}
+		if w, err = o.Rotation.writer(o.Path, open); err != nil {
+			return Sink{}, fmt.Errorf("rotation: %w", err)
+		}
+	default:
+		return Sink{}, fmt.Errorf("unknown type %q", o.Type)
+	}
+	return NewSink(w, sinkOpts...), nil
+}
+
+// writer 创建写入 path 的 writer，r 为 nil 时不切割，open 为 false 时只检查配置
+func (r *RotationConfig) writer(path string, open bool) (io.Writer, error) {
+	if r == nil {
+		if !open {
+			return nil, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+
+	var maxAge time.Duration
+	if r.MaxAge != "" {
+		d, err := time.ParseDuration(r.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("maxAge: %w", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("maxAge must be positive, got %s", r.MaxAge)
+		}
+		maxAge = d
+	}
+
+	switch r.By {
+	case "size":
+		if r.RotationTime != "" || r.Format != "" {
+			return nil, fmt.Errorf("rotationTime and format require by age")
+		}
+		if r.MaxSize < 0 || r.MaxBackups < 0 {
+			return nil, fmt.Errorf("maxSize and maxBackups must not be negative")
+		}
+		var opts []SplitBySizeOption
+		if r.MaxSize > 0 {
+			opts = append(opts, SplitBySizeWithMaxSize(r.MaxSize))
+		}
+		if r.MaxBackups > 0 {
+			opts = append(opts, SplitBySizeWithMaxBackups(r.MaxBackups))
+		}
+		if maxAge > 0 {
+			opts = append(opts, SplitBySizeWithMaxAge(int((maxAge+24*time.Hour-1)/(24*time.Hour))))
+		}
+		if r.Compress != nil {
+			opts = append(opts, SplitBySizeWithCompress(*r.Compress))
+		}
+		if !open {
+			return nil, nil
+		}
+		return NewWriterWithSize(path, opts...), nil
+	case "age":
+		if r.MaxSize != 0 || r.MaxBackups != 0 || r.Compress != nil {
+			return nil, fmt.Errorf("maxSize, maxBackups and compress require by size")
+		}
+		var opts []SplitByAgeOption
+		if maxAge > 0 {
+			opts = append(opts, SplitByAgeWithMaxAge(maxAge))
+		}
+		if r.RotationTime != "" {
+			d, err := time.ParseDuration(r.RotationTime)
+			if err != nil {
+				return nil, fmt.Errorf("rotationTime: %w", err)
+			}
+			if d <= 0 {
+				return nil, fmt.Errorf("rotationTime must be positive, got %s", r.RotationTime)
+			}
+			opts = append(opts, SplitByAgeWithRotationDuration(d))
+		}
+		if r.Format != "" {
+			opts = append(opts, SplitByAgeWithFormat(r.Format))
+		}
+		if !open {
+			return nil, nil
+		}
+		return NewWriterWithAgeE(path, opts...)
+	default:
+		return nil, fmt.Errorf("unknown by %q, want size or age", r.By)
+	}
+}
+
+func (r SamplingRule) option() (Option, error) {
+	sc := SamplingConfig{Tick: time.Second, First: r.First, Thereafter: r.Thereafter}
+	if r.Tick != "" {
+		d, err := time.ParseDuration(r.Tick)
+		if err != nil {
+			return nil, fmt.Errorf("tick: %w", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("tick must be positive, got %s", r.Tick)
+		}
+		sc.Tick = d
+	}
+	if r.First < 0 || r.Thereafter < 0 {
+		return nil, fmt.Errorf("first and thereafter must not be negative")
+	}
+	var levels []zapcore.Level
+	for _, s := range r.Levels {
+		l, err := parseLevel(s, zapcore.DebugLevel)
+		if err != nil {
+			return nil, fmt.Errorf("levels: %w", err)
+		}
+		levels = append(levels, l)
+	}
+	return ConfigWithSampling(sc, levels...), nil
+}
+
+// parseLevel 解析级别，s 为空时返回 def
+func parseLevel(s string, def zapcore.Level) (zapcore.Level, error) {
+	if s == "" {
+		return def, nil
+	}
+	return zapcore.ParseLevel(s)
+}
+
+// parseEncoder 解析编码，s 为空时返回 def
+func parseEncoder(s string, def Encoder) (Encoder, error) {
+	switch e := Encoder(strings.ToLower(s)); e {
+	case "":
+		return def, nil
+	case EncoderJson, EncoderConsole:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown encoder %q, want json or console", s)
+	}
+}
@@ -0,0 +1,197 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.yaml")
+	logPath := filepath.Join(dir, "logs", "app.log")
+	data := `
+level: info
+encoder: json
+caller: false
+outputs:
+  - type: file
+    path: ` + logPath + `
+    minLevel: info
+sampling:
+  - {levels: [info], tick: 1m, first: 2, thereafter: 0}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = InitWithConfig(c); err != nil {
+		t.Fatal(err)
+	}
+	defer Init()
+
+	Debug("hidden")
+	for i := 0; i < 5; i++ {
+		Info("repeated")
+	}
+	Warn("warn")
+	Sync()
+
+	out, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"M":"repeated"`) || !strings.Contains(lines[2], `"M":"warn"`) {
+		t.Fatalf("output: %s", out)
+	}
+	if strings.Contains(string(out), `"C":`) {
+		t.Fatalf("caller not disabled: %s", out)
+	}
+	if GetLevel() != zapcore.InfoLevel {
+		t.Fatalf("level: %s", GetLevel())
+	}
+}
+
+func TestConfigApplyEnv(t *testing.T) {
+	c, err := ParseConfig([]byte(`{"level":"info","outputs":[{"type":"stderr"}]}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_LOG_LEVEL", "warn")
+	t.Setenv("APP_LOG_CALLER", "false")
+	t.Setenv("APP_LOG_OUTPUTS", "stdout, ./app.log")
+	t.Setenv("APP_LOG_SAMPLING_FIRST", "10")
+	if err = c.ApplyEnv("APP_LOG"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "warn" || c.Caller == nil || *c.Caller {
+		t.Fatalf("config: %+v", c)
+	}
+	if len(c.Outputs) != 2 || c.Outputs[0].Type != "stdout" || c.Outputs[1].Type != "file" || c.Outputs[1].Path != "./app.log" {
+		t.Fatalf("outputs: %+v", c.Outputs)
+	}
+	if len(c.Sampling) != 1 || c.Sampling[0].First != 10 {
+		t.Fatalf("sampling: %+v", c.Sampling)
+	}
+	if err = c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("APP_LOG_CALLER", "maybe")
+	if err = c.ApplyEnv("APP_LOG"); err == nil {
+		t.Fatal("want error for invalid bool")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"level", `level: verbose`, "level"},
+		{"encoder", `encoder: xml`, "encoder"},
+		{"output type", `outputs: [{type: kafka}]`, "outputs[0]: unknown type"},
+		{"missing path", `outputs: [{type: file}]`, "outputs[0]: path is required"},
+		{"level range", `outputs: [{minLevel: error, maxLevel: info}]`, "outputs[0]: minLevel"},
+		{"rotation by", `outputs: [{type: file, path: a.log, rotation: {by: day}}]`, "unknown by"},
+		{"rotation time", `outputs: [{type: file, path: a.log, rotation: {by: age, rotationTime: daily}}]`, "rotationTime"},
+		{"rotation mixed", `outputs: [{type: file, path: a.log, rotation: {by: age, maxSize: 10}}]`, "require by size"},
+		{"max age", `outputs: [{type: file, path: a.log, rotation: {by: size, maxAge: -1h}}]`, "maxAge"},
+		{"sampling tick", `sampling: [{tick: 0s}]`, "sampling[0]: tick"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseConfig([]byte(tt.yaml), "yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseConfig([]byte("levle: info"), "yaml"); err == nil {
+		t.Fatal("want error for unknown field")
+	}
+	if _, err := ParseConfig([]byte("level = info"), "toml"); err == nil {
+		t.Fatal("want error for unknown format")
+	}
+}
+
+func TestConfigRotation(t *testing.T) {
+	dir := t.TempDir()
+	c := &Config{Outputs: []OutputConfig{
+		{Type: "file", Path: filepath.Join(dir, "size.log"), Rotation: &RotationConfig{By: "size", MaxSize: 1, MaxAge: "36h"}},
+		{Type: "file", Path: filepath.Join(dir, "age.log"), Rotation: &RotationConfig{By: "age", RotationTime: "1h", MaxAge: "48h"}},
+	}}
+	opts, err := c.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewLogger(opts...).Info("rotated")
+
+	for _, name := range []string{"size.log", "age.log"} {
+		if out, err := os.ReadFile(filepath.Join(dir, name)); err != nil || !strings.Contains(string(out), "rotated") {
+			t.Fatalf("%s: %q %v", name, out, err)
+		}
+	}
+}
+
+func TestNewWriterWithAgeE(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "age.log")
+	if _, err := NewWriterWithAgeE(name, SplitByAgeWithRotationTime("daily")); err == nil {
+		t.Fatal("want error for invalid rotation time")
+	}
+	if _, err := NewWriterWithAgeE(name, SplitByAgeWithRotationDuration(0)); err == nil {
+		t.Fatal("want error for zero rotation time")
+	}
+	if _, err := NewWriterWithAgeE(name, SplitByAgeWithRotationDuration(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigOptionsNoLeak(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.log")
+
+	// 配置错误时不创建文件
+	c := &Config{Outputs: []OutputConfig{
+		{Type: "file", Path: first},
+		{Type: "file", Path: filepath.Join(dir, "second.log"), Rotation: &RotationConfig{By: "day"}},
+	}}
+	if _, err := c.Options(); err == nil {
+		t.Fatal("want error for invalid rotation")
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Fatalf("first.log created: %v", err)
+	}
+
+	// 打开失败时关闭已经打开的文件
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c.Outputs[1] = OutputConfig{Type: "file", Path: filepath.Join(dir, "file", "second.log")}
+	if _, err := c.Options(); err == nil {
+		t.Fatal("want error for unwritable path")
+	}
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	for _, fd := range fds {
+		if target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); target == first {
+			t.Fatalf("first.log left open")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type ctxKey struct{}
+
+// ctxValue context 中携带的 logger 和字段
+type ctxValue struct {
+	logger *zap.SugaredLogger
+	fields []interface{}
+
+	// logger 中已经包含的字段数以及 trace_id、span_id，输出时不再重复添加
+	loggerFields            int
+	loggerTrace, loggerSpan string
+}
+
+func valueFromContext(ctx context.Context) ctxValue {
+	if ctx == nil {
+		return ctxValue{}
+	}
+	v, _ := ctx.Value(ctxKey{}).(ctxValue)
+	return v
+}
+
+// NewContext 返回携带 kvs 字段的 context，字段会沿调用链累积，WithContext 和 InfoCtx 等函数会自动带上这些字段
+//
+//	eg: ctx = log.NewContext(ctx, "request_id", reqID, "user_id", uid)
+func NewContext(ctx context.Context, kvs ...interface{}) context.Context {
+	v := valueFromContext(ctx)
+	v.fields = append(v.fields[:len(v.fields):len(v.fields)], kvs...)
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// ContextWithLogger 返回携带 logger 的 context，WithContext 和 InfoCtx 等函数使用该 logger 代替全局日志
+//
+//	logger 需要能直接调用输出正确的调用位置，例如 WithKV/WithName 返回的 logger，ctx 中累积的字段仍会添加到 logger 上
+//	logger 由 WithContext 返回时不会重复添加其中已经包含的字段和 trace_id、span_id，eg: ctx = log.ContextWithLogger(ctx, log.WithContext(ctx))
+func ContextWithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	v := valueFromContext(ctx)
+	v.logger = logger
+	v.loggerFields, v.loggerTrace, v.loggerSpan = 0, "", ""
+	if c, ok := logger.Desugar().Core().(*ctxCore); ok {
+		v.loggerFields, v.loggerTrace, v.loggerSpan = c.fields, c.traceID, c.spanID
+		if v.loggerFields > len(v.fields) {
+			v.loggerFields = len(v.fields)
+		}
+	}
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// FieldsFromContext 返回 context 中累积的字段以及 trace_id、span_id
+func FieldsFromContext(ctx context.Context) []interface{} {
+	fields := append([]interface{}(nil), valueFromContext(ctx).fields...)
+	if ctx == nil {
+		return fields
+	}
+	traceID, spanID := TraceInfoFromContext(ctx)
+	if len(traceID) > 0 {
+		fields = append(fields, "trace_id", traceID)
+	}
+	if len(spanID) > 0 {
+		fields = append(fields, "span_id", spanID)
+	}
+	return fields
+}
+
+// loggerFromContext 返回 context 对应的 logger，直接调用时输出调用方的位置
+func loggerFromContext(ctx context.Context) *zap.SugaredLogger {
+	v := valueFromContext(ctx)
+	l := v.logger
+	if l == nil {
+		l = helper.WithOptions(zap.AddCallerSkip(-1))
+	}
+	fields := append([]interface{}(nil), v.fields[v.loggerFields:]...)
+	var traceID, spanID string
+	if ctx != nil {
+		traceID, spanID = TraceInfoFromContext(ctx)
+	}
+	if len(traceID) > 0 && traceID != v.loggerTrace {
+		fields = append(fields, "trace_id", traceID)
+	}
+	if len(spanID) > 0 && spanID != v.loggerSpan {
+		fields = append(fields, "span_id", spanID)
+	}
+	if len(fields) > 0 {
+		l = l.With(fields...)
+	}
+	if len(v.fields) > 0 || len(traceID) > 0 || len(spanID) > 0 {
+		l = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &ctxCore{Core: core, fields: len(v.fields), traceID: traceID, spanID: spanID}
+		}))
+	}
+	return l
+}
+
+// ctxCore 标记 logger 中已经包含的 context 字段数以及 trace_id、span_id，供 ContextWithLogger 判断
+type ctxCore struct {
+	zapcore.Core
+	fields          int
+	traceID, spanID string
+}
+
+func (c *ctxCore) With(fields []zapcore.Field) zapcore.Core {
+	return &ctxCore{Core: c.Core.With(fields), fields: c.fields, traceID: c.traceID, spanID: c.spanID}
+}
+
+// ctxHelper 供 InfoCtx 等函数使用，比直接调用多一层调用栈
+func ctxHelper(ctx context.Context) *zap.SugaredLogger {
+	return loggerFromContext(ctx).WithOptions(zap.AddCallerSkip(1))
+}
+
+func DebugCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).Debugw(msg, kvs...)
+}
+
+func InfoCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).Infow(msg, kvs...)
+}
+
+func WarnCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).Warnw(msg, kvs...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).Errorw(msg, kvs...)
+}
+
+func FatalCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).Fatalw(msg, kvs...)
+}
+
+func DPanicCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).DPanicw(msg, kvs...)
+}
+
+func PanicCtx(ctx context.Context, msg string, kvs ...interface{}) {
+	ctxHelper(ctx).Panicw(msg, kvs...)
+}
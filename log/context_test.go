@@ -0,0 +1,132 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithEncoder(EncoderJson))
+	defer Init()
+
+	ctx := NewContext(context.Background(), "request_id", "r1")
+	child := NewContext(ctx, "user_id", 42)
+	sibling := NewContext(ctx, "tenant", "t1")
+
+	InfoCtx(child, "handled", "status", 200)
+	WithContext(sibling).Warn("slow")
+	ErrorCtx(context.Background(), "plain")
+
+	entries := decodeLines(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("output: %s", buf.String())
+	}
+	if e := entries[0]; e["request_id"] != "r1" || e["user_id"] != float64(42) || e["status"] != float64(200) || e["tenant"] != nil {
+		t.Fatalf("child entry: %v", e)
+	}
+	if e := entries[1]; e["request_id"] != "r1" || e["tenant"] != "t1" || e["user_id"] != nil {
+		t.Fatalf("sibling entry: %v", e)
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e["C"].(string), "log/context_test.go") {
+			t.Fatalf("caller: %v", e["C"])
+		}
+	}
+	if entries[2]["request_id"] != nil {
+		t.Fatalf("plain entry: %v", entries[2])
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithEncoder(EncoderJson))
+	defer Init()
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	ctx = ContextWithLogger(ctx, WithKV("component", "scp"))
+	ctx = NewContext(ctx, "host", "10.0.0.1")
+
+	InfoCtx(ctx, "uploaded")
+	e := decodeLines(t, &buf)[0]
+	if e["component"] != "scp" || e["host"] != "10.0.0.1" || e["trace_id"] != traceID.String() || e["span_id"] != spanID.String() {
+		t.Fatalf("entry: %v", e)
+	}
+	if !strings.HasPrefix(e["C"].(string), "log/context_test.go") {
+		t.Fatalf("caller: %v", e["C"])
+	}
+}
+
+func TestContextWithContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithEncoder(EncoderJson))
+	defer Init()
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	childSpan, _ := trace.SpanIDFromHex("0807060504030201")
+	ctx := trace.ContextWithSpanContext(NewContext(context.Background(), "request_id", "r1"),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	ctx = ContextWithLogger(ctx, WithContext(ctx))
+	ctx = NewContext(ctx, "host", "10.0.0.1")
+
+	InfoCtx(ctx, "cached")
+	out := buf.String()
+	for _, key := range []string{`"request_id"`, `"host"`, `"trace_id"`, `"span_id"`} {
+		if n := strings.Count(out, key); n != 1 {
+			t.Fatalf("%s appears %d times: %s", key, n, out)
+		}
+	}
+	if kvs := FieldsFromContext(ctx); len(kvs) != 8 {
+		t.Fatalf("fields: %v", kvs)
+	}
+
+	// 进入新的 span 时添加新的 span_id，trace_id 不重复添加
+	buf.Reset()
+	ctx = trace.ContextWithSpanContext(ctx,
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: childSpan}))
+	WithContext(ctx).Info("child")
+	out = buf.String()
+	if strings.Count(out, `"trace_id"`) != 1 || !strings.Contains(out, childSpan.String()) {
+		t.Fatalf("child span: %s", out)
+	}
+}
+
+func TestContextLoggerKeepsFields(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithEncoder(EncoderJson))
+	defer Init()
+
+	ctx := NewContext(context.Background(), "request_id", "r1")
+	ctx = ContextWithLogger(ctx, WithKV("component", "db"))
+	ctx = NewContext(ctx, "host", "10.0.0.1")
+
+	InfoCtx(ctx, "query")
+	e := decodeLines(t, &buf)[0]
+	if e["component"] != "db" || e["request_id"] != "r1" || e["host"] != "10.0.0.1" {
+		t.Fatalf("entry: %v", e)
+	}
+}
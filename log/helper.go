@@ -7,16 +7,27 @@ import (
 	"go.uber.org/zap"
 )
 
-var helper *zap.SugaredLogger
+var (
+	helper *zap.SugaredLogger
+	// samplingStats 全局日志的采样统计
+	samplingStats = NewSamplingStats()
+)
 
 func init() {
 	if helper == nil {
-		helper = NewLogger()
+		Init()
 	}
 }
 
+// Init 初始化全局日志，日志级别可以通过 SetLevel 在运行时修改
 func Init(opts ...Option) {
-	helper = NewLogger(opts...)
+	helper = NewLogger(append(opts, ConfigWithAtomicLevel(level), ConfigWithSamplingStats(samplingStats))...)
+	setConfiguredLevel(level.Level())
+}
+
+// Sampling 返回全局日志的采样统计，ConfigWithSampling 丢弃的日志计入其中
+func Sampling() *SamplingStats {
+	return samplingStats
 }
 
 // WithKV must have key and value
@@ -31,20 +42,11 @@ func WithName(name string) *zap.SugaredLogger {
 	return l.Named(name)
 }
 
+// WithContext 返回带有 context 中 trace_id、span_id 以及 NewContext 累积字段的 logger
+//
+//	context 通过 ContextWithLogger 携带了 logger 时使用该 logger
 func WithContext(ctx context.Context) *zap.SugaredLogger {
-	l := helper.WithOptions(zap.AddCallerSkip(-1))
-
-	var fields []interface{}
-
-	traceID, spanID := TraceInfoFromContext(ctx)
-	if len(traceID) > 0 {
-		fields = append(fields, "trace_id", traceID)
-	}
-	if len(spanID) > 0 {
-		fields = append(fields, "span_id", spanID)
-	}
-
-	return l.With(fields...)
+	return loggerFromContext(ctx)
 }
 
 func TraceInfoFromContext(ctx context.Context) (traceID, spanID string) {
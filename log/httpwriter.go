@@ -0,0 +1,525 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPEntry 一条待发送的日志
+type HTTPEntry struct {
+	Time time.Time // 写入的时间
+	Line []byte    // 编码后的日志，不含换行
+}
+
+// HTTPEncoding 将一批日志编码为请求体
+type HTTPEncoding func(entries []HTTPEntry) (body []byte, contentType string, err error)
+
+// HTTPNDJSON 每行一条日志
+func HTTPNDJSON(entries []HTTPEntry) ([]byte, string, error) {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		buf.Write(e.Line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), "application/x-ndjson", nil
+}
+
+// HTTPElasticBulk Elasticsearch bulk 接口的格式，日志需要使用 EncoderJson
+//
+//	eg: NewHTTPWriter("http://es:9200/_bulk", HTTPWithEncoding(HTTPElasticBulk("app-log")))
+func HTTPElasticBulk(index string) HTTPEncoding {
+	action, _ := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": index}})
+	return func(entries []HTTPEntry) ([]byte, string, error) {
+		var buf bytes.Buffer
+		for _, e := range entries {
+			buf.Write(action)
+			buf.WriteByte('\n')
+			buf.Write(e.Line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+}
+
+// HTTPLoki Loki push 接口的格式，所有日志使用相同的 labels
+//
+//	eg: NewHTTPWriter("http://loki:3100/loki/api/v1/push", HTTPWithEncoding(HTTPLoki(map[string]string{"app": "api"})))
+func HTTPLoki(labels map[string]string) HTTPEncoding {
+	return func(entries []HTTPEntry) ([]byte, string, error) {
+		values := make([][2]string, 0, len(entries))
+		for _, e := range entries {
+			values = append(values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), string(e.Line)})
+		}
+		body, err := json.Marshal(map[string]interface{}{
+			"streams": []interface{}{map[string]interface{}{"stream": labels, "values": values}},
+		})
+		return body, "application/json", err
+	}
+}
+
+type HTTPConfig struct {
+	Client        *http.Client
+	Headers       http.Header
+	Encoding      HTTPEncoding
+	BatchSize     int           // 每个请求最多的日志条数
+	FlushInterval time.Duration // 不足一批时定期发送的间隔
+	QueueSize     int           // 内存中最多缓存的日志条数，超出时落盘，没有设置落盘目录时丢弃最早的日志
+	MaxRetries    int           // 请求失败后的重试次数
+	RetryBackoff  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	SpillDir      string        // 发送失败的日志落盘的目录，为空时丢弃
+	SpillMaxBytes int64         // 落盘目录的大小上限，超出时删除最早的文件
+}
+
+type HTTPOption func(*HTTPConfig)
+
+func HTTPWithClient(c *http.Client) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.Client = c
+	}
+}
+
+func HTTPWithHeader(key, value string) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.Headers.Add(key, value)
+	}
+}
+
+func HTTPWithEncoding(e HTTPEncoding) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.Encoding = e
+	}
+}
+
+func HTTPWithBatchSize(n int) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.BatchSize = n
+	}
+}
+
+func HTTPWithFlushInterval(d time.Duration) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.FlushInterval = d
+	}
+}
+
+func HTTPWithQueueSize(n int) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.QueueSize = n
+	}
+}
+
+func HTTPWithRetry(maxRetries int, backoff time.Duration) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.MaxRetries = maxRetries
+		config.RetryBackoff = backoff
+	}
+}
+
+// HTTPWithSpill 发送失败或内存队列满时将日志写入 dir，之后按顺序重新发送，程序重启后同样会发送
+func HTTPWithSpill(dir string, maxBytes int64) HTTPOption {
+	return func(config *HTTPConfig) {
+		config.SpillDir = dir
+		config.SpillMaxBytes = maxBytes
+	}
+}
+
+// httpStatusError 服务端返回的非 2xx 响应
+type httpStatusError struct {
+	code int
+	body string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.code, e.body)
+}
+
+// permanent 重试也不会成功的响应
+func (e *httpStatusError) permanent() bool {
+	return e.code >= 400 && e.code < 500 && e.code != http.StatusRequestTimeout && e.code != http.StatusTooManyRequests
+}
+
+// HTTPWriter 批量 POST 日志到 HTTP 服务，写入只进入内存队列，由后台协程发送
+//
+//	失败的请求按指数退避重试，重试后仍失败的一批日志落盘，之后先发送落盘的日志再发送新的日志
+//	Sync 会发送队列中的全部日志，程序退出前需要调用 log.Sync 或 Close
+type HTTPWriter struct {
+	url     string
+	config  *HTTPConfig
+	dropped uint64
+
+	mu      sync.Mutex
+	pending []HTTPEntry
+	closed  bool
+
+	spillMu  sync.Mutex
+	spillSeq uint64
+
+	closeErr error
+
+	wake    chan struct{}
+	flushes chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewHTTPWriter 返回发送到 url 的 writer，默认每行一条日志
+func NewHTTPWriter(url string, opts ...HTTPOption) (*HTTPWriter, error) {
+	config := &HTTPConfig{
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		Headers:       make(http.Header),
+		Encoding:      HTTPNDJSON,
+		BatchSize:     500,
+		FlushInterval: time.Second,
+		QueueSize:     10000,
+		MaxRetries:    3,
+		RetryBackoff:  500 * time.Millisecond,
+		SpillMaxBytes: 100 << 20,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.BatchSize < 1 || config.QueueSize < config.BatchSize {
+		return nil, fmt.Errorf("invalid batch size %d or queue size %d", config.BatchSize, config.QueueSize)
+	}
+	if config.FlushInterval <= 0 {
+		return nil, fmt.Errorf("flush interval must be positive, got %s", config.FlushInterval)
+	}
+	if config.SpillDir != "" {
+		if err := os.MkdirAll(config.SpillDir, 0o755); err != nil {
+			return nil, fmt.Errorf("create spill dir err: %w", err)
+		}
+	}
+
+	h := &HTTPWriter{
+		url:     url,
+		config:  config,
+		wake:    make(chan struct{}, 1),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+// Write 将一条日志放入队列
+func (h *HTTPWriter) Write(p []byte) (int, error) {
+	entry := HTTPEntry{Time: time.Now(), Line: append([]byte(nil), bytes.TrimRight(p, "\n")...)}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return 0, errors.New("http writer closed")
+	}
+	var overflow []HTTPEntry
+	if len(h.pending) >= h.config.QueueSize {
+		if h.config.SpillDir != "" {
+			overflow, h.pending = h.pending, nil
+		} else {
+			h.pending = h.pending[1:]
+			atomic.AddUint64(&h.dropped, 1)
+		}
+	}
+	h.pending = append(h.pending, entry)
+	full := len(h.pending) >= h.config.BatchSize
+	h.mu.Unlock()
+
+	if overflow != nil {
+		h.spill(overflow)
+	}
+	if full {
+		select {
+		case h.wake <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync 发送落盘和队列中的全部日志，返回发送失败的错误
+func (h *HTTPWriter) Sync() error {
+	ack := make(chan error, 1)
+	select {
+	case h.flushes <- ack:
+		return <-ack
+	case <-h.stopped:
+		return nil
+	}
+}
+
+// Close 发送剩余的日志并停止后台协程，发送失败的日志落盘
+func (h *HTTPWriter) Close() error {
+	h.once.Do(func() {
+		close(h.done)
+	})
+	<-h.stopped
+	return h.closeErr
+}
+
+// Dropped 返回被丢弃的日志条数
+func (h *HTTPWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+func (h *HTTPWriter) run() {
+	defer close(h.stopped)
+	ticker := time.NewTicker(h.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.wake:
+			h.flush(false)
+		case <-ticker.C:
+			h.flush(true)
+		case ack := <-h.flushes:
+			ack <- h.flush(true)
+		case <-h.done:
+			h.mu.Lock()
+			h.closed = true
+			h.mu.Unlock()
+			if h.closeErr = h.flush(true); h.closeErr != nil {
+				h.mu.Lock()
+				rest := h.pending
+				h.pending = nil
+				h.mu.Unlock()
+				h.spill(rest)
+			}
+			return
+		}
+	}
+}
+
+// flush 先发送落盘的日志，再发送队列中的日志，all 为 false 时只发送满一批的日志
+func (h *HTTPWriter) flush(all bool) error {
+	if err := h.replay(); err != nil {
+		return err
+	}
+	for {
+		h.mu.Lock()
+		n := len(h.pending)
+		if n == 0 || (!all && n < h.config.BatchSize) {
+			h.mu.Unlock()
+			return nil
+		}
+		if n > h.config.BatchSize {
+			n = h.config.BatchSize
+		}
+		batch := h.pending[:n:n]
+		h.pending = h.pending[n:]
+		h.mu.Unlock()
+
+		if err := h.post(batch); err != nil {
+			if se, ok := err.(*httpStatusError); ok && se.permanent() {
+				atomic.AddUint64(&h.dropped, uint64(len(batch)))
+			} else {
+				h.spill(batch)
+			}
+			return err
+		}
+	}
+}
+
+// post 发送一批日志，失败时按指数退避重试
+func (h *HTTPWriter) post(batch []HTTPEntry) error {
+	body, contentType, err := h.config.Encoding(batch)
+	if err != nil {
+		return fmt.Errorf("encode log batch err: %w", err)
+	}
+	backoff := h.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = h.do(body, contentType)
+		if err == nil {
+			return nil
+		}
+		if se, ok := err.(*httpStatusError); ok && se.permanent() {
+			return err
+		}
+		if attempt >= h.config.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *HTTPWriter) do(body []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range h.config.Headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := h.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &httpStatusError{code: resp.StatusCode, body: string(msg)}
+}
+
+// spill 将一批日志写入落盘目录，没有设置落盘目录时丢弃
+func (h *HTTPWriter) spill(batch []HTTPEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	if h.config.SpillDir == "" {
+		atomic.AddUint64(&h.dropped, uint64(len(batch)))
+		return
+	}
+
+	h.spillMu.Lock()
+	defer h.spillMu.Unlock()
+	h.spillSeq++
+	name := filepath.Join(h.config.SpillDir, fmt.Sprintf("%020d-%06d.spill", time.Now().UnixNano(), h.spillSeq%1000000))
+	if err := writeSpill(name, batch); err != nil {
+		atomic.AddUint64(&h.dropped, uint64(len(batch)))
+		return
+	}
+	h.trimSpill()
+}
+
+// trimSpill 落盘目录超出大小上限时删除最早的文件，调用方需要持有 spillMu
+func (h *HTTPWriter) trimSpill() {
+	files, err := h.spillFiles()
+	if err != nil {
+		return
+	}
+	var total int64
+	sizes := make([]int64, len(files))
+	for i, name := range files {
+		if info, err := os.Stat(name); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	for i := 0; i < len(files)-1 && total > h.config.SpillMaxBytes; i++ {
+		if data, err := os.ReadFile(files[i]); err == nil {
+			atomic.AddUint64(&h.dropped, uint64(bytes.Count(data, []byte("\n"))))
+		}
+		os.Remove(files[i])
+		total -= sizes[i]
+	}
+}
+
+// replay 按顺序发送落盘的日志，发送成功后删除
+func (h *HTTPWriter) replay() error {
+	if h.config.SpillDir == "" {
+		return nil
+	}
+	h.spillMu.Lock()
+	files, err := h.spillFiles()
+	h.spillMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("read spill dir err: %w", err)
+	}
+
+	for _, name := range files {
+		batch, err := readSpill(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// 已经被 trimSpill 删除
+				continue
+			}
+			return fmt.Errorf("read spill file err: %w", err)
+		}
+		for len(batch) > 0 {
+			n := len(batch)
+			if n > h.config.BatchSize {
+				n = h.config.BatchSize
+			}
+			if err = h.post(batch[:n]); err != nil {
+				se, ok := err.(*httpStatusError)
+				if !ok || !se.permanent() {
+					// 剩余的日志重新写回文件，已经发送的不再重复发送
+					h.spillMu.Lock()
+					writeSpill(name, batch)
+					h.spillMu.Unlock()
+					return err
+				}
+				atomic.AddUint64(&h.dropped, uint64(n))
+			}
+			batch = batch[n:]
+		}
+		h.spillMu.Lock()
+		os.Remove(name)
+		h.spillMu.Unlock()
+	}
+	return nil
+}
+
+// spillEntry 落盘文件中的一行
+type spillEntry struct {
+	Time int64  `json:"t"`
+	Line string `json:"l"`
+}
+
+// writeSpill 将一批日志写入 name，先写临时文件再重命名，避免重放时读到写了一半的文件
+func writeSpill(name string, batch []HTTPEntry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range batch {
+		if err := enc.Encode(spillEntry{Time: e.Time.UnixNano(), Line: string(e.Line)}); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(name+".tmp", buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		os.Remove(name + ".tmp")
+		return err
+	}
+	return nil
+}
+
+// spillFiles 返回落盘的文件，按写入顺序排列
+func (h *HTTPWriter) spillFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(h.config.SpillDir, "*.spill"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func readSpill(name string) ([]HTTPEntry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var batch []HTTPEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var e spillEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// 跳过损坏的行
+			continue
+		}
+		batch = append(batch, HTTPEntry{Time: time.Unix(0, e.Time), Line: []byte(e.Line)})
+	}
+	return batch, scanner.Err()
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// logServer 记录收到的日志，fail 大于 0 时返回 503 并减一
+type logServer struct {
+	mu       sync.Mutex
+	lines    []string
+	requests int32
+	fail     int32
+	down     int32
+}
+
+func (s *logServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&s.requests, 1)
+	if atomic.LoadInt32(&s.down) == 1 || atomic.AddInt32(&s.fail, -1) >= 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for scanner.Scan() {
+		s.lines = append(s.lines, scanner.Text())
+	}
+}
+
+func (s *logServer) messages(t *testing.T) []string {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []string
+	for _, line := range s.lines {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		msgs = append(msgs, entry["M"].(string))
+	}
+	return msgs
+}
+
+func TestHTTPWriterRetry(t *testing.T) {
+	s := &logServer{fail: 2}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	w, err := NewHTTPWriter(srv.URL, HTTPWithBatchSize(2), HTTPWithRetry(3, time.Millisecond), HTTPWithHeader("X-Token", "t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewLogger(ConfigWithSinks(NewSink(w, SinkWithEncoder(EncoderJson))))
+	logger.Info("a")
+	logger.Info("b")
+	logger.Info("c")
+	if err = logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(s.messages(t), ","); got != "a,b,c" {
+		t.Fatalf("messages: %s", got)
+	}
+	// 第一批失败两次后成功，第二批一次成功
+	if n := atomic.LoadInt32(&s.requests); n != 4 {
+		t.Fatalf("requests: %d", n)
+	}
+	if _, err = w.Write([]byte("closed")); err == nil {
+		t.Fatal("want error after close")
+	}
+}
+
+func TestHTTPWriterSpill(t *testing.T) {
+	s := &logServer{down: 1}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	dir := filepath.Join(t.TempDir(), "spill")
+
+	w, err := NewHTTPWriter(srv.URL, HTTPWithRetry(0, 0), HTTPWithSpill(dir, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewLogger(ConfigWithSinks(NewSink(w, SinkWithEncoder(EncoderJson))))
+	logger.Info("a")
+	if err = w.Sync(); err == nil {
+		t.Fatal("want error while server is down")
+	}
+	logger.Info("b")
+	if err = w.Close(); err == nil {
+		t.Fatal("want error while server is down")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.spill")); len(files) != 2 {
+		t.Fatalf("spill files: %v", files)
+	}
+
+	// 重启后先发送落盘的日志
+	atomic.StoreInt32(&s.down, 0)
+	w, err = NewHTTPWriter(srv.URL, HTTPWithRetry(0, 0), HTTPWithSpill(dir, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte(`{"M":"c"}` + "\n"))
+	if err = w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(s.messages(t), ","); got != "a,b,c" {
+		t.Fatalf("messages: %s", got)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Fatalf("spill files left: %v", files)
+	}
+	if w.Dropped() != 0 {
+		t.Fatalf("dropped: %d", w.Dropped())
+	}
+}
+
+func TestHTTPWriterDrop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	w, err := NewHTTPWriter(srv.URL, HTTPWithRetry(3, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("a\n"))
+	w.Write([]byte("b\n"))
+	// 4xx 不重试，直接丢弃
+	if err = w.Sync(); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("err: %v", err)
+	}
+	if w.Dropped() != 2 {
+		t.Fatalf("dropped: %d", w.Dropped())
+	}
+}
+
+func TestHTTPEncoding(t *testing.T) {
+	entries := []HTTPEntry{{Time: time.Unix(1, 5), Line: []byte(`{"M":"a"}`)}}
+
+	body, contentType, _ := HTTPElasticBulk("app")(entries)
+	if string(body) != `{"index":{"_index":"app"}}`+"\n"+`{"M":"a"}`+"\n" || contentType != "application/x-ndjson" {
+		t.Fatalf("bulk: %q %s", body, contentType)
+	}
+
+	body, contentType, _ = HTTPLoki(map[string]string{"app": "api"})(entries)
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(body, &push); err != nil || contentType != "application/json" {
+		t.Fatalf("loki: %s %v", body, err)
+	}
+	if s := push.Streams[0]; s.Stream["app"] != "api" || s.Values[0] != [2]string{"1000000005", `{"M":"a"}`} {
+		t.Fatalf("loki: %s", body)
+	}
+}
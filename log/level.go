@@ -0,0 +1,84 @@
+package log
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// level 全局日志的级别
+	level = zap.NewAtomicLevel()
+
+	levelMu sync.Mutex
+	// configured Init 时配置的级别，ToggleDebug 在该级别和 Debug 之间切换
+	configured zapcore.Level
+)
+
+func setConfiguredLevel(l zapcore.Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	configured = l
+}
+
+// SetLevel 修改全局日志的级别，立即生效
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// GetLevel 返回全局日志当前的级别
+func GetLevel() zapcore.Level {
+	return level.Level()
+}
+
+// LevelHandler 返回查询和修改全局日志级别的 http.Handler，与 zap.AtomicLevel 的接口相同
+//
+//	GET 返回 {"level":"info"}
+//	PUT 请求体为 {"level":"debug"}，或表单 level=debug
+//	eg: http.Handle("/log/level", log.LevelHandler())
+func LevelHandler() http.Handler {
+	return level
+}
+
+// ToggleDebug 在 Init 配置的级别和 Debug 之间切换，返回切换后的级别
+func ToggleDebug() zapcore.Level {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	next := zapcore.DebugLevel
+	if level.Level() == zapcore.DebugLevel {
+		next = configured
+	}
+	level.SetLevel(next)
+	return next
+}
+
+// ToggleDebugOnSignal 收到信号时调用 ToggleDebug，返回停止监听的函数
+//
+//	eg: defer log.ToggleDebugOnSignal(syscall.SIGUSR1)()
+func ToggleDebugOnSignal(sig ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				Warnf("log level changed to %s", ToggleDebug())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
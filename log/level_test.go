@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithLevel(zapcore.InfoLevel))
+	defer Init()
+
+	Debug("hidden")
+	SetLevel(zapcore.DebugLevel)
+	Debug("shown")
+	if GetLevel() != zapcore.DebugLevel {
+		t.Fatalf("level = %s, want debug", GetLevel())
+	}
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Fatalf("output: %s", buf.String())
+	}
+
+	// 重新 Init 时恢复为配置的级别
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithLevel(zapcore.WarnLevel))
+	if GetLevel() != zapcore.WarnLevel {
+		t.Fatalf("level = %s, want warn", GetLevel())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	Init(ConfigWithWriters([]io.Writer{io.Discard}), ConfigWithLevel(zapcore.InfoLevel))
+	defer Init()
+
+	server := httptest.NewServer(LevelHandler())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(`{"level":"error"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || GetLevel() != zapcore.ErrorLevel {
+		t.Fatalf("status %d, level %s", resp.StatusCode, GetLevel())
+	}
+
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.TrimSpace(string(body)) != `{"level":"error"}` {
+		t.Fatalf("body = %s", body)
+	}
+}
+
+func TestToggleDebug(t *testing.T) {
+	Init(ConfigWithWriters([]io.Writer{io.Discard}), ConfigWithLevel(zapcore.WarnLevel))
+	defer Init()
+
+	if l := ToggleDebug(); l != zapcore.DebugLevel {
+		t.Fatalf("level = %s, want debug", l)
+	}
+	if l := ToggleDebug(); l != zapcore.WarnLevel {
+		t.Fatalf("level = %s, want warn", l)
+	}
+
+	stop := ToggleDebugOnSignal(os.Interrupt)
+	defer stop()
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skip("sending signal not supported: ", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for GetLevel() != zapcore.DebugLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %s after signal, want debug", GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
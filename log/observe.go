@@ -0,0 +1,196 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestingT testing.T 和 testing.B 中 Observe 用到的方法
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// ObservedEntry 一条记录的日志
+type ObservedEntry struct {
+	Level      zapcore.Level
+	Time       time.Time
+	LoggerName string
+	Message    string
+	Caller     string                 // 与日志中的 C 相同，eg: "log/observe_test.go:12"
+	Stack      string                 // 达到 ConfigWithStacktraceLevel 级别时的调用栈
+	Fields     map[string]interface{} // 包括 WithKV、Infow 以及 context 中的字段
+}
+
+// ObservedLogs 记录的日志，Filter 开头的方法返回过滤后的快照，可以链式调用
+type ObservedLogs struct {
+	t    TestingT
+	logs *observer.ObservedLogs
+}
+
+// Observe 将全局日志替换为只在内存中记录日志的 logger，测试结束时恢复原来的全局日志和级别
+//
+//	opts 与 Init 相同，默认记录 Debug 及以上级别，脱敏、采样等配置同样生效
+//	替换的是全局变量，使用 Observe 的测试不能并行
+//	eg:
+//	logs := log.Observe(t)
+//	handle()
+//	logs.FilterLevel(zapcore.ErrorLevel).AssertLen(0)
+//	logs.AssertLogged(zapcore.InfoLevel, "handled", "status", 200)
+func Observe(t TestingT, opts ...Option) *ObservedLogs {
+	t.Helper()
+
+	prevHelper, prevLevel := helper, level.Level()
+	levelMu.Lock()
+	prevConfigured := configured
+	levelMu.Unlock()
+
+	var logs *observer.ObservedLogs
+	Init(append(opts, func(config *config) {
+		config.extraCores = append(config.extraCores, func(level zap.AtomicLevel) zapcore.Core {
+			var core zapcore.Core
+			core, logs = observer.New(level)
+			return core
+		})
+	})...)
+
+	t.Cleanup(func() {
+		helper = prevHelper
+		level.SetLevel(prevLevel)
+		setConfiguredLevel(prevConfigured)
+	})
+	return &ObservedLogs{t: t, logs: logs}
+}
+
+// All 返回全部日志
+func (o *ObservedLogs) All() []ObservedEntry {
+	logged := o.logs.All()
+	entries := make([]ObservedEntry, 0, len(logged))
+	for _, e := range logged {
+		entries = append(entries, newObservedEntry(e))
+	}
+	return entries
+}
+
+func (o *ObservedLogs) Len() int {
+	return o.logs.Len()
+}
+
+// TakeAll 返回并清空全部日志，只能在 Observe 返回的 ObservedLogs 上调用
+func (o *ObservedLogs) TakeAll() []ObservedEntry {
+	logged := o.logs.TakeAll()
+	entries := make([]ObservedEntry, 0, len(logged))
+	for _, e := range logged {
+		entries = append(entries, newObservedEntry(e))
+	}
+	return entries
+}
+
+// Messages 返回全部日志的消息，方便整体比较
+func (o *ObservedLogs) Messages() []string {
+	var msgs []string
+	for _, e := range o.logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// FilterLevel 只保留级别为 l 的日志
+func (o *ObservedLogs) FilterLevel(l zapcore.Level) *ObservedLogs {
+	return &ObservedLogs{t: o.t, logs: o.logs.FilterLevelExact(l)}
+}
+
+// FilterMessage 只保留消息为 msg 的日志
+func (o *ObservedLogs) FilterMessage(msg string) *ObservedLogs {
+	return &ObservedLogs{t: o.t, logs: o.logs.FilterMessage(msg)}
+}
+
+// FilterMessageContains 只保留消息包含 sub 的日志
+func (o *ObservedLogs) FilterMessageContains(sub string) *ObservedLogs {
+	return &ObservedLogs{t: o.t, logs: o.logs.FilterMessageSnippet(sub)}
+}
+
+// FilterField 只保留带有字段 key 且值为 value 的日志，值按 fmt.Sprint 的结果比较，1 和 int64(1) 相等
+func (o *ObservedLogs) FilterField(key string, value interface{}) *ObservedLogs {
+	want := fmt.Sprint(value)
+	return o.Filter(func(e ObservedEntry) bool {
+		v, ok := e.Fields[key]
+		return ok && fmt.Sprint(v) == want
+	})
+}
+
+// FilterFieldKey 只保留带有字段 key 的日志
+func (o *ObservedLogs) FilterFieldKey(key string) *ObservedLogs {
+	return &ObservedLogs{t: o.t, logs: o.logs.FilterFieldKey(key)}
+}
+
+// Filter 只保留 keep 返回 true 的日志
+func (o *ObservedLogs) Filter(keep func(ObservedEntry) bool) *ObservedLogs {
+	return &ObservedLogs{t: o.t, logs: o.logs.Filter(func(e observer.LoggedEntry) bool {
+		return keep(newObservedEntry(e))
+	})}
+}
+
+// AssertLen 断言日志条数为 n
+func (o *ObservedLogs) AssertLen(n int) bool {
+	o.t.Helper()
+	if got := o.logs.Len(); got != n {
+		o.t.Errorf("want %d log entries, got %d:\n%s", n, got, o)
+		return false
+	}
+	return true
+}
+
+// AssertLogged 断言存在级别为 l、消息为 msg 且带有 kvs 字段的日志
+func (o *ObservedLogs) AssertLogged(l zapcore.Level, msg string, kvs ...interface{}) bool {
+	o.t.Helper()
+	matched := o.FilterLevel(l).FilterMessage(msg)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		matched = matched.FilterField(fmt.Sprint(kvs[i]), kvs[i+1])
+	}
+	if matched.Len() == 0 {
+		o.t.Errorf("no %s log %q with fields %v in:\n%s", l.CapitalString(), msg, kvs, o)
+		return false
+	}
+	return true
+}
+
+// AssertNotLogged 断言不存在级别为 l 且消息包含 sub 的日志
+func (o *ObservedLogs) AssertNotLogged(l zapcore.Level, sub string) bool {
+	o.t.Helper()
+	if matched := o.FilterLevel(l).FilterMessageContains(sub); matched.Len() > 0 {
+		o.t.Errorf("unexpected %s log containing %q:\n%s", l.CapitalString(), sub, matched)
+		return false
+	}
+	return true
+}
+
+// String 每行一条日志，用于断言失败时输出
+func (o *ObservedLogs) String() string {
+	var b strings.Builder
+	for _, e := range o.All() {
+		fmt.Fprintf(&b, "\t%s\t%s\t%s\t%v\n", e.Level.CapitalString(), e.Caller, e.Message, e.Fields)
+	}
+	return b.String()
+}
+
+func newObservedEntry(e observer.LoggedEntry) ObservedEntry {
+	entry := ObservedEntry{
+		Level:      e.Level,
+		Time:       e.Time,
+		LoggerName: e.LoggerName,
+		Message:    e.Message,
+		Stack:      e.Stack,
+		Fields:     e.ContextMap(),
+	}
+	if e.Caller.Defined {
+		entry.Caller = e.Caller.TrimmedPath()
+	}
+	return entry
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeT 记录断言失败，用于测试断言本身
+type fakeT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func TestObserve(t *testing.T) {
+	prev := helper
+	prevLevel := GetLevel()
+
+	t.Run("observe", func(t *testing.T) {
+		logs := Observe(t, ConfigWithRedaction())
+
+		Debugw("debug msg", "password", "p@ss")
+		WithKV("user", "u1").Infow("handled", "status", 200)
+		InfoCtx(NewContext(context.Background(), "request_id", "r1"), "ctx msg")
+		WithName("db").Warn("slow query")
+		Errorf("failed: %d", 3)
+
+		if logs.Len() != 5 {
+			t.Fatalf("entries:\n%s", logs)
+		}
+		e := logs.All()[1]
+		if e.Level != zapcore.InfoLevel || e.Message != "handled" || e.Fields["user"] != "u1" || e.Fields["status"] != int64(200) {
+			t.Fatalf("entry: %+v", e)
+		}
+		if !strings.HasPrefix(e.Caller, "log/observe_test.go:") {
+			t.Fatalf("caller: %s", e.Caller)
+		}
+		if logs.All()[0].Fields["password"] != redactMask {
+			t.Fatalf("not redacted: %v", logs.All()[0].Fields)
+		}
+
+		logs.AssertLogged(zapcore.InfoLevel, "handled", "status", 200, "user", "u1")
+		logs.AssertLogged(zapcore.InfoLevel, "ctx msg", "request_id", "r1")
+		logs.AssertNotLogged(zapcore.ErrorLevel, "panic")
+		logs.FilterLevel(zapcore.ErrorLevel).AssertLen(1)
+		logs.FilterFieldKey("user").AssertLen(1)
+		logs.Filter(func(e ObservedEntry) bool { return e.LoggerName == "db" }).AssertLen(1)
+		if got := logs.FilterMessageContains("msg").Messages(); strings.Join(got, ",") != "debug msg,ctx msg" {
+			t.Fatalf("messages: %v", got)
+		}
+
+		if len(logs.TakeAll()) != 5 || logs.Len() != 0 {
+			t.Fatal("TakeAll did not reset")
+		}
+		SetLevel(zapcore.ErrorLevel)
+	})
+
+	if helper != prev || GetLevel() != prevLevel {
+		t.Fatal("global logger not restored")
+	}
+}
+
+func TestObserveAssertFailures(t *testing.T) {
+	ft := &fakeT{}
+	logs := Observe(ft, ConfigWithLevel(zapcore.InfoLevel))
+	defer func() {
+		for _, fn := range ft.cleanups {
+			fn()
+		}
+	}()
+
+	Debug("hidden")
+	Infow("handled", "status", 500)
+	Error("boom")
+
+	if logs.AssertLogged(zapcore.InfoLevel, "handled", "status", 200) ||
+		logs.AssertNotLogged(zapcore.ErrorLevel, "boom") ||
+		logs.AssertLen(3) {
+		t.Fatal("assertions passed")
+	}
+	if len(ft.errors) != 3 || !strings.Contains(ft.errors[0], "handled") || !strings.Contains(ft.errors[0], "status:500") {
+		t.Fatalf("errors: %q", ft.errors)
+	}
+	if !logs.AssertLogged(zapcore.InfoLevel, "handled", "status", 500) || len(ft.errors) != 3 {
+		t.Fatalf("errors: %q", ft.errors)
+	}
+}
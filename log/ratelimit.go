@@ -0,0 +1,113 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxLimiterKeys RateLimiter 记录的 key 超过该数量时清理已过期的 key
+const maxLimiterKeys = 1024
+
+// RateLimiter 按 key 限制日志的输出频率，每个 key 每个 interval 周期内最多输出 burst 条
+//
+//	eg: limiter := NewRateLimiter(time.Minute, 1)
+//	limiter.Logger("retry:" + host).Errorw("retry failed", "err", err)
+type RateLimiter struct {
+	interval time.Duration
+	burst    int
+
+	mu      sync.Mutex
+	windows map[string]*limitWindow
+}
+
+type limitWindow struct {
+	start      time.Time
+	count      int
+	suppressed uint64 // 当前周期内被限制的条数
+	carry      uint64 // 上一个周期被限制、尚未报告的条数
+}
+
+func NewRateLimiter(interval time.Duration, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		interval: interval,
+		burst:    burst,
+		windows:  make(map[string]*limitWindow),
+	}
+}
+
+// Allow 判断 key 当前是否可以输出日志
+func (r *RateLimiter) Allow(key string) bool {
+	ok, _ := r.allow(key)
+	return ok
+}
+
+// allow 返回是否可以输出，以及之前被限制且尚未报告的条数
+func (r *RateLimiter) allow(key string) (bool, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	w, ok := r.windows[key]
+	if !ok {
+		if len(r.windows) >= maxLimiterKeys {
+			r.cleanup(now)
+		}
+		w = &limitWindow{start: now}
+		r.windows[key] = w
+	}
+	if now.Sub(w.start) >= r.interval {
+		w.start, w.count = now, 0
+		w.carry += w.suppressed
+		w.suppressed = 0
+	}
+	if w.count >= r.burst {
+		w.suppressed++
+		return false, 0
+	}
+	w.count++
+	suppressed := w.carry
+	w.carry = 0
+	return true, suppressed
+}
+
+// cleanup 删除已过期且没有未报告条数的 key
+func (r *RateLimiter) cleanup(now time.Time) {
+	for key, w := range r.windows {
+		if now.Sub(w.start) >= r.interval && w.suppressed == 0 && w.carry == 0 {
+			delete(r.windows, key)
+		}
+	}
+}
+
+// Suppressed 返回每个 key 被限制的条数，包含当前周期内的条数
+func (r *RateLimiter) Suppressed() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := make(map[string]uint64)
+	for key, w := range r.windows {
+		if n := w.carry + w.suppressed; n > 0 {
+			m[key] = n
+		}
+	}
+	return m
+}
+
+// Logger 返回 key 对应的全局日志，被限制时返回丢弃所有日志的 logger
+//
+//	之前有被限制的日志时，输出的日志带有 suppressed 字段记录被限制的条数
+func (r *RateLimiter) Logger(key string) *zap.SugaredLogger {
+	ok, suppressed := r.allow(key)
+	if !ok {
+		return zap.NewNop().Sugar()
+	}
+	l := helper.WithOptions(zap.AddCallerSkip(-1))
+	if suppressed > 0 {
+		l = l.With("suppressed", suppressed)
+	}
+	return l
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(50*time.Millisecond, 2)
+
+	for i, want := range []bool{true, true, false, false} {
+		if got := limiter.Allow("a"); got != want {
+			t.Fatalf("allow %d = %v, want %v", i, got, want)
+		}
+	}
+	if !limiter.Allow("b") {
+		t.Fatal("key b limited by key a")
+	}
+	if s := limiter.Suppressed(); s["a"] != 2 || s["b"] != 0 {
+		t.Fatalf("suppressed %v", s)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !limiter.Allow("a") {
+		t.Fatal("key a still limited after interval")
+	}
+}
+
+func TestRateLimiterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}))
+	defer Init()
+
+	limiter := NewRateLimiter(50*time.Millisecond, 1)
+	for i := 0; i < 3; i++ {
+		limiter.Logger("retry").Errorw("retry failed", "attempt", i)
+	}
+	time.Sleep(60 * time.Millisecond)
+	limiter.Logger("retry").Errorw("retry failed", "attempt", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output: %s", buf.String())
+	}
+	if !strings.Contains(lines[1], `"suppressed": 2`) || !strings.Contains(lines[1], "ratelimit_test.go") {
+		t.Fatalf("line: %s", lines[1])
+	}
+	if len(limiter.Suppressed()) != 0 {
+		t.Fatalf("suppressed %v", limiter.Suppressed())
+	}
+}
@@ -0,0 +1,322 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type RedactMode int
+
+const (
+	RedactMask RedactMode = iota // 替换为 ******
+	RedactHash                   // 替换为 sha256 摘要的前 12 位，相同的值可以关联但无法还原
+)
+
+const redactMask = "******"
+
+// defaultRedactKeys 默认脱敏的字段名，不区分大小写
+var defaultRedactKeys = []string{
+	"password", "passwd", "pwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "cookie", "private_key",
+}
+
+// defaultRedactPatterns 默认脱敏的内容，有分组时只替换第一个分组
+var defaultRedactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`(?i)\bBearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`),
+	regexp.MustCompile(`\b1[3-9]\d{9}\b`),
+}
+
+type redactConfig struct {
+	keys     []string
+	patterns []*regexp.Regexp
+	mode     RedactMode
+}
+
+type RedactOption func(*redactConfig)
+
+// RedactWithKeys 追加需要脱敏的字段名，不区分大小写
+func RedactWithKeys(keys ...string) RedactOption {
+	return func(config *redactConfig) {
+		config.keys = append(config.keys, keys...)
+	}
+}
+
+// RedactWithPatterns 追加需要脱敏的内容，有分组时只替换第一个分组
+//
+//	eg: regexp.MustCompile(`card=(\d+)`) 只替换卡号，保留 card=
+func RedactWithPatterns(patterns ...*regexp.Regexp) RedactOption {
+	return func(config *redactConfig) {
+		config.patterns = append(config.patterns, patterns...)
+	}
+}
+
+func RedactWithMode(m RedactMode) RedactOption {
+	return func(config *redactConfig) {
+		config.mode = m
+	}
+}
+
+// ConfigWithRedaction 对日志中的敏感信息脱敏
+//
+//	字段名匹配的字段（Infow/WithKV 的 kv 等）整个值被替换，字符串和 error 字段的值以及日志消息中匹配的内容被替换
+//	结构体、map、切片和 ObjectMarshaler 等字段按 json 展开后递归处理，其中字段名匹配的值和字符串中匹配的内容被替换
+//	日志消息中 key=value、key: value 形式且 key 匹配的 value 也会被替换
+//	默认包含常见的密码、token 字段名，以及私钥、Bearer token、JWT、AWS AccessKey、URL 中的密码和手机号
+func ConfigWithRedaction(opts ...RedactOption) Option {
+	return func(config *config) {
+		rc := &redactConfig{
+			keys:     append([]string(nil), defaultRedactKeys...),
+			patterns: append([]*regexp.Regexp(nil), defaultRedactPatterns...),
+		}
+		for _, opt := range opts {
+			opt(rc)
+		}
+		config.redactor = newRedactor(rc)
+	}
+}
+
+type redactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+	mode     RedactMode
+}
+
+func newRedactor(rc *redactConfig) *redactor {
+	r := &redactor{
+		keys:     make(map[string]bool, len(rc.keys)),
+		patterns: rc.patterns,
+		mode:     rc.mode,
+	}
+	quoted := make([]string, 0, len(rc.keys))
+	for _, key := range rc.keys {
+		r.keys[strings.ToLower(key)] = true
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	if len(quoted) > 0 {
+		// 消息中的 key=value、key: value、"key":"value"
+		kv := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b["']?\s*[:=]\s*["']?([^\s"',;&]+)`)
+		r.patterns = append(r.patterns, kv)
+	}
+	return r
+}
+
+// redact 按模式替换整个值
+func (r *redactor) redact(v string) string {
+	if r.mode == RedactHash {
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return redactMask
+}
+
+// replace 替换 s 中所有匹配的内容
+func (r *redactor) replace(s string) string {
+	for _, p := range r.patterns {
+		matches := p.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.redact(s[start:end]))
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// fields 返回脱敏后的字段，没有需要脱敏的字段时返回原切片
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, ok := r.field(f)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = redacted
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.SkipType {
+		return f, false
+	}
+	if r.keys[strings.ToLower(f.Key)] {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		return zap.String(f.Key, r.redact(fmt.Sprint(enc.Fields[f.Key]))), true
+	}
+
+	var s string
+	switch f.Type {
+	case zapcore.ReflectType:
+		if v, ok := r.walk(f.Interface); ok {
+			return zap.Any(f.Key, v), true
+		}
+		return f, false
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.InlineMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if f.Type == zapcore.InlineMarshalerType {
+			if v, ok := r.walk(enc.Fields); ok {
+				return zap.Inline(redactedObject(v.(map[string]interface{}))), true
+			}
+		} else if v, ok := r.walk(enc.Fields[f.Key]); ok {
+			return zap.Any(f.Key, v), true
+		}
+		return f, false
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType:
+		s = string(f.Interface.([]byte))
+	case zapcore.ErrorType, zapcore.StringerType:
+		s = fmt.Sprint(f.Interface)
+	default:
+		return f, false
+	}
+	if replaced := r.replace(s); replaced != s {
+		return zap.String(f.Key, replaced), true
+	}
+	return f, false
+}
+
+// walk 递归替换 v 中字段名匹配的值和字符串中匹配的内容，没有替换时返回 false
+//
+//	MapObjectEncoder 中 AddReflected 的值以及其他无法直接遍历的值先按 json 展开，与 json 编码器的输出一致
+func (r *redactor) walk(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil, bool, json.Number, time.Time, time.Duration,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, complex64, complex128:
+		return v, false
+	case string:
+		replaced := r.replace(v)
+		return replaced, replaced != v
+	case map[string]interface{}:
+		var out map[string]interface{}
+		for k, val := range v {
+			var redacted interface{}
+			var ok bool
+			if r.keys[strings.ToLower(k)] {
+				redacted, ok = r.redact(fmt.Sprint(val)), true
+			} else {
+				redacted, ok = r.walk(val)
+			}
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]interface{}, len(v))
+				for k, val := range v {
+					out[k] = val
+				}
+			}
+			out[k] = redacted
+		}
+		return out, out != nil
+	case []interface{}:
+		var out []interface{}
+		for i, val := range v {
+			redacted, ok := r.walk(val)
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = append([]interface{}(nil), v...)
+			}
+			out[i] = redacted
+		}
+		return out, out != nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	if err = dec.Decode(&decoded); err != nil {
+		return v, false
+	}
+	switch decoded.(type) {
+	case string, map[string]interface{}, []interface{}:
+		return r.walk(decoded)
+	}
+	return v, false
+}
+
+// redactedObject 脱敏后重新输出 zap.Inline 的字段
+type redactedObject map[string]interface{}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := enc.AddReflected(k, o[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactCore 写入之前对日志消息和字段脱敏，需要包装在最终写入的 core 上以保留其级别判断
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func newRedactCore(core zapcore.Core, r *redactor) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &redactCore{Core: core, r: r}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.replace(ent.Message)
+	return c.Core.Write(ent, c.r.fields(fields))
+}
@@ -0,0 +1,167 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// maxDroppedMessages SamplingStats 最多分别统计的不同消息数，超出后只计入总数和级别
+const maxDroppedMessages = 1000
+
+// SamplingConfig 采样配置，每个 Tick 周期内同一级别的同一条消息先输出 First 条，之后每 Thereafter 条输出一条
+type SamplingConfig struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int // 为 0 时丢弃 First 之后的全部日志
+}
+
+type samplingRule struct {
+	config SamplingConfig
+	levels []zapcore.Level
+}
+
+// ConfigWithSampling 对 levels 级别的日志按 sc 采样，不指定 levels 时作用于所有级别
+//
+//	可以多次调用为不同级别设置不同的采样，同一级别以最后一次为准
+//	eg: 每秒每条 Error 消息先输出 10 条，之后每 100 条输出一条
+//	ConfigWithSampling(SamplingConfig{Tick: time.Second, First: 10, Thereafter: 100}, zapcore.ErrorLevel)
+func ConfigWithSampling(sc SamplingConfig, levels ...zapcore.Level) Option {
+	return func(config *config) {
+		config.sampling = append(config.sampling, samplingRule{config: sc, levels: levels})
+	}
+}
+
+// ConfigWithSamplingStats 将采样丢弃的日志计入 stats
+func ConfigWithSamplingStats(stats *SamplingStats) Option {
+	return func(config *config) {
+		config.samplingStats = stats
+	}
+}
+
+// SamplingStats 采样丢弃的日志统计，可以并发使用
+type SamplingStats struct {
+	mu        sync.Mutex
+	total     uint64
+	byLevel   map[zapcore.Level]uint64
+	byMessage map[string]uint64
+}
+
+func NewSamplingStats() *SamplingStats {
+	return &SamplingStats{
+		byLevel:   make(map[zapcore.Level]uint64),
+		byMessage: make(map[string]uint64),
+	}
+}
+
+func (s *SamplingStats) add(ent zapcore.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	s.byLevel[ent.Level]++
+	if _, ok := s.byMessage[ent.Message]; ok || len(s.byMessage) < maxDroppedMessages {
+		s.byMessage[ent.Message]++
+	}
+}
+
+// Dropped 返回丢弃的日志总数
+func (s *SamplingStats) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+// DroppedByLevel 返回每个级别丢弃的日志数
+func (s *SamplingStats) DroppedByLevel() map[zapcore.Level]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[zapcore.Level]uint64, len(s.byLevel))
+	for k, v := range s.byLevel {
+		m[k] = v
+	}
+	return m
+}
+
+// DroppedByMessage 返回每条消息丢弃的日志数，最多统计 1000 条不同的消息
+func (s *SamplingStats) DroppedByMessage() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]uint64, len(s.byMessage))
+	for k, v := range s.byMessage {
+		m[k] = v
+	}
+	return m
+}
+
+// Reset 清空统计
+func (s *SamplingStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total = 0
+	s.byLevel = make(map[zapcore.Level]uint64)
+	s.byMessage = make(map[string]uint64)
+}
+
+// newSamplingCore 按级别为 core 添加采样，没有采样配置的级别保持不变
+func newSamplingCore(core zapcore.Core, rules []samplingRule, stats *SamplingStats) zapcore.Core {
+	if len(rules) == 0 {
+		return core
+	}
+
+	byLevel := make(map[zapcore.Level]SamplingConfig)
+	for _, rule := range rules {
+		levels := rule.levels
+		if len(levels) == 0 {
+			for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+				levels = append(levels, l)
+			}
+		}
+		for _, l := range levels {
+			byLevel[l] = rule.config
+		}
+	}
+
+	hook := zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+		if dec&zapcore.LogDropped != 0 && stats != nil {
+			stats.add(ent)
+		}
+	})
+
+	var cores []zapcore.Core
+	for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+		sc, ok := byLevel[l]
+		if !ok {
+			continue
+		}
+		sampledLevel := l
+		filtered := levelFilterCore{Core: core, enabled: func(l zapcore.Level) bool { return l == sampledLevel }}
+		cores = append(cores, zapcore.NewSamplerWithOptions(filtered, sc.Tick, sc.First, sc.Thereafter, hook))
+	}
+	cores = append(cores, levelFilterCore{Core: core, enabled: func(l zapcore.Level) bool {
+		_, sampled := byLevel[l]
+		return !sampled
+	}})
+	return zapcore.NewTee(cores...)
+}
+
+// levelFilterCore 只处理 enabled 返回 true 的级别
+type levelFilterCore struct {
+	zapcore.Core
+	enabled func(zapcore.Level) bool
+}
+
+func (c levelFilterCore) Enabled(l zapcore.Level) bool {
+	return c.enabled(l) && c.Core.Enabled(l)
+}
+
+func (c levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelFilterCore{Core: c.Core.With(fields), enabled: c.enabled}
+}
+
+func (c levelFilterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	Init(
+		ConfigWithWriters([]io.Writer{&buf}),
+		ConfigWithSampling(SamplingConfig{Tick: time.Minute, First: 2, Thereafter: 3}),
+		ConfigWithSampling(SamplingConfig{Tick: time.Minute, First: 2}, zapcore.ErrorLevel),
+	)
+	defer Init()
+	Sampling().Reset()
+
+	for i := 0; i < 10; i++ {
+		Error("boom")
+		Info("tick")
+	}
+	Error("other")
+
+	out := buf.String()
+	if n := strings.Count(out, "boom"); n != 2 {
+		t.Fatalf("boom logged %d times, want 2", n)
+	}
+	// 前 2 条，之后第 3、6 条
+	if n := strings.Count(out, "tick"); n != 4 {
+		t.Fatalf("tick logged %d times, want 4", n)
+	}
+	if !strings.Contains(out, "other") {
+		t.Fatal("other message sampled by boom")
+	}
+
+	stats := Sampling()
+	if stats.Dropped() != 14 {
+		t.Fatalf("dropped %d, want 14", stats.Dropped())
+	}
+	if byLevel := stats.DroppedByLevel(); byLevel[zapcore.ErrorLevel] != 8 || byLevel[zapcore.InfoLevel] != 6 {
+		t.Fatalf("dropped by level %v", byLevel)
+	}
+	if byMessage := stats.DroppedByMessage(); byMessage["boom"] != 8 || byMessage["tick"] != 6 {
+		t.Fatalf("dropped by message %v", byMessage)
+	}
+}
+
+func TestSamplingWith(t *testing.T) {
+	var buf bytes.Buffer
+	Init(
+		ConfigWithWriters([]io.Writer{&buf}),
+		ConfigWithSampling(SamplingConfig{Tick: time.Minute, First: 1}, zapcore.WarnLevel),
+	)
+	defer Init()
+
+	l := WithKV("host", "a")
+	for i := 0; i < 3; i++ {
+		l.Warn("retry")
+		l.Info("connected")
+	}
+	out := buf.String()
+	if strings.Count(out, "retry") != 1 || strings.Count(out, "connected") != 3 || strings.Count(out, `"host": "a"`) != 4 {
+		t.Fatalf("output: %s", out)
+	}
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink 一个独立的日志输出，只写入级别在 [minLevel, maxLevel] 范围内的日志
+type Sink struct {
+	writer   io.Writer
+	encoder  Encoder
+	minLevel zapcore.Level
+	maxLevel zapcore.Level
+}
+
+type SinkOption func(*Sink)
+
+// SinkWithEncoder 设置输出的编码，默认与 ConfigWithEncoder 相同
+func SinkWithEncoder(e Encoder) SinkOption {
+	return func(sink *Sink) {
+		sink.encoder = e
+	}
+}
+
+// SinkWithMinLevel 设置输出的最低级别，默认 Debug，全局级别更高时以全局级别为准
+func SinkWithMinLevel(l zapcore.Level) SinkOption {
+	return func(sink *Sink) {
+		sink.minLevel = l
+	}
+}
+
+// SinkWithMaxLevel 设置输出的最高级别，默认 Fatal
+//
+//	eg: SinkWithMaxLevel(zapcore.DebugLevel) 只输出 Debug 日志
+func SinkWithMaxLevel(l zapcore.Level) SinkOption {
+	return func(sink *Sink) {
+		sink.maxLevel = l
+	}
+}
+
+// NewSink 创建写入 w 的输出，w 可以是 NewWriterWithAge/NewWriterWithSize 创建的切割文件
+func NewSink(w io.Writer, opts ...SinkOption) Sink {
+	sink := Sink{
+		writer:   w,
+		minLevel: zapcore.DebugLevel,
+		maxLevel: zapcore.FatalLevel,
+	}
+	for _, opt := range opts {
+		opt(&sink)
+	}
+	return sink
+}
+
+func (s Sink) core(encoder Encoder, level zap.AtomicLevel) zapcore.Core {
+	if s.encoder != "" {
+		encoder = s.encoder
+	}
+	enabler := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return level.Enabled(l) && l >= s.minLevel && l <= s.maxLevel
+	})
+	if lw, ok := s.writer.(LevelWriter); ok {
+		return &levelCore{LevelEnabler: enabler, enc: newEncoder(encoder), w: lw}
+	}
+	return zapcore.NewCore(newEncoder(encoder), zapcore.AddSync(s.writer), enabler)
+}
+
+// LevelWriter 需要知道日志级别的 writer，Sink 的 writer 实现该接口时每条日志通过 WriteLevel 写入
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(l zapcore.Level, p []byte) (int, error)
+}
+
+// levelCore 与 zapcore.NewCore 相同，写入时带上日志级别
+type levelCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   LevelWriter
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &levelCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *levelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	_, err = c.w.WriteLevel(ent.Level, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// 与 zapcore.NewCore 相同，Panic/Fatal 之前写入
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *levelCore) Sync() error {
+	if s, ok := c.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
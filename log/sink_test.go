@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSinks(t *testing.T) {
+	var all, debug, errs bytes.Buffer
+	Init(ConfigWithSinks(
+		NewSink(&all),
+		NewSink(&debug, SinkWithMaxLevel(zapcore.DebugLevel)),
+		NewSink(&errs, SinkWithEncoder(EncoderJson), SinkWithMinLevel(zapcore.ErrorLevel)),
+	))
+	defer Init()
+
+	Debug("debug msg")
+	Info("info msg")
+	Error("error msg")
+
+	if strings.Count(all.String(), "\n") != 3 {
+		t.Fatalf("all: %s", all.String())
+	}
+	if !strings.Contains(debug.String(), "debug msg") || strings.Count(debug.String(), "\n") != 1 {
+		t.Fatalf("debug: %s", debug.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(errs.Bytes(), &entry); err != nil {
+		t.Fatalf("errs: %s, err: %v", errs.String(), err)
+	}
+	if entry["M"] != "error msg" || entry["L"] != "ERROR" {
+		t.Fatalf("errs: %v", entry)
+	}
+
+	// 全局级别同样作用于各个输出
+	SetLevel(zapcore.InfoLevel)
+	Debug("debug msg")
+	if strings.Count(debug.String(), "\n") != 1 {
+		t.Fatalf("debug: %s", debug.String())
+	}
+}
+
+func TestSinkWithFile(t *testing.T) {
+	var stdout bytes.Buffer
+	name := filepath.Join(t.TempDir(), "error.log")
+	w := NewWriterWithSize(name)
+	Init(
+		ConfigWithWriters([]io.Writer{&stdout}),
+		ConfigWithSinks(NewSink(w, SinkWithMinLevel(zapcore.ErrorLevel))),
+	)
+	defer Init()
+
+	Info("info msg")
+	Error("error msg")
+	if closer, ok := w.(io.Closer); ok {
+		closer.Close()
+	}
+
+	if strings.Count(stdout.String(), "\n") != 2 {
+		t.Fatalf("stdout: %s", stdout.String())
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "error msg") || strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("error.log: %s", data)
+	}
+}
@@ -0,0 +1,279 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogHandler 返回写入全局日志的 slog.Handler，与全局日志共用输出、字段布局和 context 中的字段
+//
+//	Init 之后需要重新获取
+//	eg: slog.SetDefault(slog.New(log.SlogHandler()))
+func SlogHandler() slog.Handler {
+	return NewSlogHandler(helper)
+}
+
+// NewSlogHandler 返回写入 l 的 slog.Handler，调用位置取自 slog.Record，context 中的 trace_id 等字段会自动带上
+func NewSlogHandler(l *zap.SugaredLogger) slog.Handler {
+	core := l.Desugar().Core()
+	return &slogHandler{core: core, top: core}
+}
+
+// ConfigWithSlogHandler 将日志同时输出到 h，h 可以是任意 slog.Handler
+//
+//	只设置了 slog.Handler 时不再默认输出到标准输出
+func ConfigWithSlogHandler(h slog.Handler) Option {
+	return func(config *config) {
+		config.extraCores = append(config.extraCores, func(level zap.AtomicLevel) zapcore.Core {
+			return &slogCore{handler: h, level: level}
+		})
+	}
+}
+
+// slogHandler 将 slog.Record 转换为 zap 的日志写入 core
+type slogHandler struct {
+	core    zapcore.Core
+	top     zapcore.Core    // 第一个分组之前的 core，context 中的字段添加在这一层
+	grouped []zapcore.Field // 第一个分组及之后添加的字段，分组对应 zap.Namespace
+}
+
+func (h *slogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return h.core.Enabled(slogToZapLevel(l))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:   slogToZapLevel(r.Level),
+		Time:    r.Time,
+		Message: r.Message,
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ent.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zapcore.Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+
+	// context 中的字段不属于任何分组
+	core := h.core
+	if kvs := FieldsFromContext(ctx); len(kvs) > 0 {
+		if len(h.grouped) == 0 {
+			fields = append(fields, kvsToFields(kvs)...)
+		} else {
+			core = h.top.With(kvsToFields(kvs)).With(h.grouped)
+		}
+	}
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zapcore.Field
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	clone := *h
+	clone.core = h.core.With(fields)
+	if len(h.grouped) == 0 {
+		clone.top = clone.core
+	} else {
+		clone.grouped = append(h.grouped[:len(h.grouped):len(h.grouped)], fields...)
+	}
+	return &clone
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	ns := zap.Namespace(name)
+	clone.core = h.core.With([]zapcore.Field{ns})
+	clone.grouped = append(h.grouped[:len(h.grouped):len(h.grouped)], ns)
+	return &clone
+}
+
+// appendAttr 将 slog.Attr 转换为 zap 字段，空的 Attr 被忽略，key 为空的分组展开到当前层级
+func appendAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			for _, f := range appendAttr(nil, slog.Attr{Value: slog.GroupValue(attrs...)}) {
+				f.AddTo(enc)
+			}
+			return nil
+		})))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
+
+// kvsToFields 将 key/value 交替的参数转换为 zap 字段
+func kvsToFields(kvs []interface{}) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(kvs)/2)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		if !ok {
+			continue
+		}
+		fields = append(fields, zap.Any(key, kvs[i+1]))
+	}
+	return fields
+}
+
+// slogCore 将 zap 的日志转换为 slog.Record 交给 slog.Handler 处理
+type slogCore struct {
+	handler slog.Handler
+	level   zap.AtomicLevel
+}
+
+func (c *slogCore) Enabled(l zapcore.Level) bool {
+	return c.level.Enabled(l) && c.handler.Enabled(context.Background(), zapToSlogLevel(l))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.handler = withFields(c.handler, fields)
+	return &clone
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	h := c.handler
+	if ent.LoggerName != "" {
+		h = h.WithAttrs([]slog.Attr{slog.String("logger", ent.LoggerName)})
+	}
+	r := slog.NewRecord(ent.Time, zapToSlogLevel(ent.Level), ent.Message, ent.Caller.PC)
+	addFields(&r, fields)
+	return h.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// withFields 将 zap 字段添加到 handler，zap.Namespace 对应 slog 的分组
+func withFields(h slog.Handler, fields []zapcore.Field) slog.Handler {
+	var attrs []slog.Attr
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			if len(attrs) > 0 {
+				h = h.WithAttrs(attrs)
+				attrs = nil
+			}
+			h = h.WithGroup(f.Key)
+			continue
+		}
+		attrs = append(attrs, fieldToAttrs(f)...)
+	}
+	if len(attrs) > 0 {
+		h = h.WithAttrs(attrs)
+	}
+	return h
+}
+
+// addFields 将 zap 字段添加到 record，遇到 zap.Namespace 时之后的字段放入分组
+func addFields(r *slog.Record, fields []zapcore.Field) {
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			var rest slog.Record
+			addFields(&rest, fields[i+1:])
+			var attrs []slog.Attr
+			rest.Attrs(func(a slog.Attr) bool {
+				attrs = append(attrs, a)
+				return true
+			})
+			r.AddAttrs(slog.Attr{Key: f.Key, Value: slog.GroupValue(attrs...)})
+			return
+		}
+		r.AddAttrs(fieldToAttrs(f)...)
+	}
+}
+
+// fieldToAttrs 将 zap 字段转换为 slog.Attr
+func fieldToAttrs(f zapcore.Field) []slog.Attr {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	attrs := make([]slog.Attr, 0, len(enc.Fields))
+	// error 字段可能额外带有 errorVerbose，保证 key 本身在前
+	if v, ok := enc.Fields[f.Key]; ok {
+		attrs = append(attrs, slog.Any(f.Key, v))
+	}
+	for k, v := range enc.Fields {
+		if k != f.Key {
+			attrs = append(attrs, slog.Any(k, v))
+		}
+	}
+	return attrs
+}
+
+func zapToSlogLevel(l zapcore.Level) slog.Level {
+	return slog.Level(int(l) * 4)
+}
+
+func slogToZapLevel(l slog.Level) zapcore.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case l < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case l < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
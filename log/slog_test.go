@@ -0,0 +1,75 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithWriters([]io.Writer{&buf}), ConfigWithEncoder(EncoderJson), ConfigWithLevel(zapcore.InfoLevel))
+	defer Init()
+
+	logger := slog.New(SlogHandler())
+	ctx := NewContext(context.Background(), "request_id", "r1")
+
+	logger.Debug("hidden")
+	logger.InfoContext(ctx, "hello", "user", "u1", slog.Int("n", 3))
+	logger.With("svc", "api").WithGroup("req").WarnContext(ctx, "slow", "path", "/a", slog.Group("q", "k", "v"))
+	logger.Error("failed", "err", io.EOF)
+
+	entries := decodeLines(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("output: %s", buf.String())
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e["C"].(string), "log/slog_test.go") {
+			t.Fatalf("caller: %v", e["C"])
+		}
+	}
+	if e := entries[0]; e["L"] != "INFO" || e["M"] != "hello" || e["user"] != "u1" || e["n"] != float64(3) || e["request_id"] != "r1" {
+		t.Fatalf("info entry: %v", e)
+	}
+	e := entries[1]
+	req, _ := e["req"].(map[string]interface{})
+	if e["L"] != "WARN" || e["svc"] != "api" || e["request_id"] != "r1" || req == nil || req["path"] != "/a" || req["request_id"] != nil {
+		t.Fatalf("warn entry: %v", e)
+	}
+	if q, _ := req["q"].(map[string]interface{}); q["k"] != "v" {
+		t.Fatalf("group entry: %v", e)
+	}
+	if e := entries[2]; e["L"] != "ERROR" || e["err"] != "EOF" {
+		t.Fatalf("error entry: %v", e)
+	}
+}
+
+func TestConfigWithSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	Init(ConfigWithSlogHandler(slog.NewJSONHandler(&buf, nil)), ConfigWithLevel(zapcore.InfoLevel))
+	defer Init()
+
+	Debug("hidden")
+	WithKV("svc", "api").Infow("hello", "user", "u1")
+	WithName("db").Warn("slow")
+	Sync()
+
+	entries := decodeLines(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("output: %s", buf.String())
+	}
+	if e := entries[0]; e["level"] != "INFO" || e["msg"] != "hello" || e["svc"] != "api" || e["user"] != "u1" {
+		t.Fatalf("info entry: %v", e)
+	}
+	if e := entries[1]; e["level"] != "WARN" || e["logger"] != "db" {
+		t.Fatalf("warn entry: %v", e)
+	}
+}
@@ -0,0 +1,226 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type SyslogFacility int
+
+const (
+	FacilityKern   SyslogFacility = 0
+	FacilityUser   SyslogFacility = 1
+	FacilityDaemon SyslogFacility = 3
+	FacilityAuth   SyslogFacility = 4
+	FacilityLocal0 SyslogFacility = 16
+	FacilityLocal1 SyslogFacility = 17
+	FacilityLocal2 SyslogFacility = 18
+	FacilityLocal3 SyslogFacility = 19
+	FacilityLocal4 SyslogFacility = 20
+	FacilityLocal5 SyslogFacility = 21
+	FacilityLocal6 SyslogFacility = 22
+	FacilityLocal7 SyslogFacility = 23
+)
+
+type SyslogConfig struct {
+	Facility    SyslogFacility
+	Hostname    string        // 默认 os.Hostname
+	AppName     string        // 默认程序名
+	MsgID       string        // 默认 "-"
+	DialTimeout time.Duration // 连接超时
+}
+
+type SyslogOption func(*SyslogConfig)
+
+func SyslogWithFacility(f SyslogFacility) SyslogOption {
+	return func(config *SyslogConfig) {
+		config.Facility = f
+	}
+}
+
+func SyslogWithHostname(hostname string) SyslogOption {
+	return func(config *SyslogConfig) {
+		config.Hostname = hostname
+	}
+}
+
+func SyslogWithAppName(name string) SyslogOption {
+	return func(config *SyslogConfig) {
+		config.AppName = name
+	}
+}
+
+func SyslogWithMsgID(id string) SyslogOption {
+	return func(config *SyslogConfig) {
+		config.MsgID = id
+	}
+}
+
+func SyslogWithDialTimeout(d time.Duration) SyslogOption {
+	return func(config *SyslogConfig) {
+		config.DialTimeout = d
+	}
+}
+
+// SyslogWriter 以 RFC5424 格式发送日志到 syslog 服务
+//
+//	作为 Sink 的 writer 时按日志级别设置 severity，直接调用 Write 时为 info
+//	tcp、unix 使用 RFC6587 的长度前缀分帧，udp、unixgram 每条日志一个报文
+//	写入失败时重新连接并重试一次
+type SyslogWriter struct {
+	network string
+	addr    string
+	config  *SyslogConfig
+	header  string // 每条日志固定的 HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA 部分
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter 连接 syslog 服务，network 为 udp/tcp/unix/unixgram
+//
+//	eg: NewSink(w, SinkWithEncoder(EncoderJson), SinkWithMinLevel(zapcore.InfoLevel))
+func NewSyslogWriter(network, addr string, opts ...SyslogOption) (*SyslogWriter, error) {
+	switch network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+
+	hostname, _ := os.Hostname()
+	config := &SyslogConfig{
+		Facility:    FacilityUser,
+		Hostname:    hostname,
+		AppName:     filepath.Base(os.Args[0]),
+		DialTimeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.Facility < 0 || config.Facility > FacilityLocal7 {
+		return nil, fmt.Errorf("invalid syslog facility %d", config.Facility)
+	}
+
+	w := &SyslogWriter{
+		network: network,
+		addr:    addr,
+		config:  config,
+		header: strings.Join([]string{
+			syslogField(config.Hostname, 255),
+			syslogField(config.AppName, 48),
+			strconv.Itoa(os.Getpid()),
+			syslogField(config.MsgID, 32),
+			"-",
+		}, " "),
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 以 info 级别写入一条日志
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zapcore.InfoLevel, p)
+}
+
+// WriteLevel 以 l 对应的 severity 写入一条日志
+func (w *SyslogWriter) WriteLevel(l zapcore.Level, p []byte) (int, error) {
+	msg := w.format(l, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, fmt.Errorf("write syslog err: %w", err)
+	}
+	return len(p), nil
+}
+
+// Close 关闭连接
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// connect 建立连接，调用方需要持有锁
+func (w *SyslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.addr, w.config.DialTimeout)
+	if err != nil {
+		return fmt.Errorf("dial syslog err: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// format 生成 RFC5424 格式的报文，流式连接时加上长度前缀
+func (w *SyslogWriter) format(l zapcore.Level, p []byte) []byte {
+	pri := int(w.config.Facility)*8 + syslogSeverity(l)
+	msg := fmt.Sprintf("<%d>1 %s %s %s", pri, time.Now().Format("2006-01-02T15:04:05.000000Z07:00"), w.header, strings.TrimRight(string(p), "\n"))
+	if w.network == "tcp" || w.network == "unix" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	return []byte(msg)
+}
+
+func syslogSeverity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	case zapcore.FatalLevel:
+		return 0
+	}
+	return 5
+}
+
+// syslogField 返回 header 中的字段，空值为 "-"，只保留可打印的 ASCII 字符
+func syslogField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var syslogRe = regexp.MustCompile(`^<(\d+)>1 \S+ host app \d+ - - (.*)$`)
+
+func TestSyslogWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := NewSyslogWriter("udp", pc.LocalAddr().String(), SyslogWithFacility(FacilityLocal0), SyslogWithHostname("host"), SyslogWithAppName("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	logger := NewLogger(ConfigWithSinks(NewSink(w, SinkWithEncoder(EncoderJson))))
+	logger.Info("info msg")
+	logger.Error("error msg")
+
+	want := []struct {
+		pri int
+		msg string
+	}{{16*8 + 6, "info msg"}, {16*8 + 3, "error msg"}}
+	buf := make([]byte, 4096)
+	for _, w := range want {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := syslogRe.FindStringSubmatch(string(buf[:n]))
+		if m == nil || m[1] != strconv.Itoa(w.pri) || !strings.Contains(m[2], `"M":"`+w.msg+`"`) {
+			t.Fatalf("message: %q", buf[:n])
+		}
+	}
+}
+
+func TestSyslogWriterStream(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		t.Run(network, func(t *testing.T) {
+			addr := "127.0.0.1:0"
+			if network == "unix" {
+				addr = filepath.Join(t.TempDir(), "syslog.sock")
+			}
+			ln, err := net.Listen(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			frames := make(chan string, 2)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					// RFC6587 octet counting: "长度 报文"
+					length, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(length))
+					frame := make([]byte, n)
+					if _, err = io.ReadFull(r, frame); err != nil {
+						return
+					}
+					frames <- string(frame)
+				}
+			}()
+
+			w, err := NewSyslogWriter(network, ln.Addr().String(), SyslogWithHostname("host"), SyslogWithAppName("app"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			w.WriteLevel(zapcore.WarnLevel, []byte("first\n"))
+			w.Write([]byte("second"))
+
+			for _, want := range []string{"<12>1 ", "<14>1 "} {
+				select {
+				case frame := <-frames:
+					if !strings.HasPrefix(frame, want) || syslogRe.FindStringSubmatch(frame) == nil || strings.HasSuffix(frame, "\n") {
+						t.Fatalf("frame: %q", frame)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout")
+				}
+			}
+		})
+	}
+}
+
+func TestNewSyslogWriterErr(t *testing.T) {
+	if _, err := NewSyslogWriter("http", "127.0.0.1:514"); err == nil {
+		t.Fatal("want error for unsupported network")
+	}
+	if _, err := NewSyslogWriter("unix", filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Fatal("want dial error")
+	}
+}
@@ -0,0 +1,145 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TCPConfig struct {
+	DialTimeout  time.Duration // 连接超时
+	WriteTimeout time.Duration // 每次写入的超时
+	MinBackoff   time.Duration // 连接失败后第一次重连的间隔，之后每次翻倍
+	MaxBackoff   time.Duration // 重连间隔的上限
+}
+
+type TCPOption func(*TCPConfig)
+
+func TCPWithDialTimeout(d time.Duration) TCPOption {
+	return func(config *TCPConfig) {
+		config.DialTimeout = d
+	}
+}
+
+func TCPWithWriteTimeout(d time.Duration) TCPOption {
+	return func(config *TCPConfig) {
+		config.WriteTimeout = d
+	}
+}
+
+func TCPWithBackoff(min, max time.Duration) TCPOption {
+	return func(config *TCPConfig) {
+		config.MinBackoff = min
+		config.MaxBackoff = max
+	}
+}
+
+// TCPWriter 通过 TCP 发送按行分隔的日志，配合 SinkWithEncoder(EncoderJson) 即为 NDJSON
+//
+//	连接断开后在下一次写入时重连，连续失败时按指数退避，退避期间的日志被丢弃并计入 Dropped
+//	写入会阻塞在网络上，不希望影响业务时包装在 NewAsyncWriter 中使用
+type TCPWriter struct {
+	addr    string
+	config  *TCPConfig
+	dropped uint64
+
+	mu        sync.Mutex
+	conn      net.Conn
+	backoff   time.Duration
+	nextRetry time.Time
+	closed    bool
+}
+
+// NewTCPWriter 返回发送到 addr 的 writer，第一次写入时建立连接
+func NewTCPWriter(addr string, opts ...TCPOption) *TCPWriter {
+	config := &TCPConfig{
+		DialTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		MinBackoff:   100 * time.Millisecond,
+		MaxBackoff:   30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return &TCPWriter{addr: addr, config: config}
+}
+
+// Write 发送一行日志，p 不以换行结尾时补上换行
+func (w *TCPWriter) Write(p []byte) (int, error) {
+	line := p
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		line = append(append(make([]byte, 0, len(p)+1), p...), '\n')
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, fmt.Errorf("tcp writer closed")
+	}
+	if w.conn != nil {
+		if err := w.write(line); err == nil {
+			return len(p), nil
+		}
+		// 对端可能已经重启，立即重连一次
+		w.conn.Close()
+		w.conn = nil
+		w.nextRetry = time.Time{}
+	}
+	if time.Now().Before(w.nextRetry) {
+		atomic.AddUint64(&w.dropped, 1)
+		return len(p), nil
+	}
+
+	conn, err := net.DialTimeout("tcp", w.addr, w.config.DialTimeout)
+	if err == nil {
+		w.conn = conn
+		if err = w.write(line); err == nil {
+			w.backoff = 0
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	w.fail()
+	atomic.AddUint64(&w.dropped, 1)
+	return 0, fmt.Errorf("tcp writer %s err: %w", w.addr, err)
+}
+
+// Dropped 返回因连接失败被丢弃的日志条数
+func (w *TCPWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close 关闭连接，之后的写入返回错误
+func (w *TCPWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// write 写入当前连接，调用方需要持有锁
+func (w *TCPWriter) write(line []byte) error {
+	if w.config.WriteTimeout > 0 {
+		w.conn.SetWriteDeadline(time.Now().Add(w.config.WriteTimeout))
+	}
+	_, err := w.conn.Write(line)
+	return err
+}
+
+// fail 记录一次连接失败并计算下次重连的时间，调用方需要持有锁
+func (w *TCPWriter) fail() {
+	if w.backoff == 0 {
+		w.backoff = w.config.MinBackoff
+	} else if w.backoff *= 2; w.backoff > w.config.MaxBackoff {
+		w.backoff = w.config.MaxBackoff
+	}
+	w.nextRetry = time.Now().Add(w.backoff)
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveLines 接受连接并将收到的每一行发送到 lines
+func serveLines(ln net.Listener, lines chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+	}
+}
+
+func TestTCPWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	lines := make(chan string, 100)
+	go serveLines(ln, lines)
+
+	w := NewTCPWriter(addr, TCPWithBackoff(10*time.Millisecond, 50*time.Millisecond))
+	defer w.Close()
+	logger := NewLogger(ConfigWithSinks(NewSink(w, SinkWithEncoder(EncoderJson))))
+	logger.Infow("before", "n", 1)
+
+	select {
+	case line := <-lines:
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry["M"] != "before" || entry["n"] != float64(1) {
+			t.Fatalf("line: %q %v", line, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	// 服务端重启，写入在重连之后继续送达
+	ln.Close()
+	w.mu.Lock()
+	w.conn.Close()
+	w.mu.Unlock()
+	if _, err := w.Write([]byte("lost\n")); err == nil {
+		t.Fatal("want error while server is down")
+	}
+	if _, err := w.Write([]byte("backoff\n")); err != nil || w.Dropped() != 2 {
+		t.Fatalf("err: %v, dropped: %d", err, w.Dropped())
+	}
+
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveLines(ln, lines)
+
+	deadline := time.After(5 * time.Second)
+	for {
+		logger.Info("after")
+		select {
+		case line := <-lines:
+			if !json.Valid([]byte(line)) {
+				t.Fatalf("line: %q", line)
+			}
+			return
+		case <-deadline:
+			t.Fatal("not reconnected")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"strings"
 	"time"
@@ -9,9 +10,19 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// NewWriterWithAge 根据时间切割日志
+// NewWriterWithAge 根据时间切割日志，参数错误时 panic，需要处理错误时使用 NewWriterWithAgeE
 // logName eg: "./tmp" or "./tmp.log"
 func NewWriterWithAge(logName string, opts ...SplitByAgeOption) io.Writer {
+	writer, err := NewWriterWithAgeE(logName, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return writer
+}
+
+// NewWriterWithAgeE 根据时间切割日志，参数错误时返回错误
+// logName eg: "./tmp" or "./tmp.log"
+func NewWriterWithAgeE(logName string, opts ...SplitByAgeOption) (io.Writer, error) {
 	suffix := ".log"
 	if strings.HasSuffix(logName, suffix) {
 		logName, _, _ = strings.Cut(logName, suffix)
@@ -25,6 +36,12 @@ func NewWriterWithAge(logName string, opts ...SplitByAgeOption) io.Writer {
 	for _, opt := range opts {
 		opt(config)
 	}
+	if config.err != nil {
+		return nil, config.err
+	}
+	if config.RotationTime <= 0 {
+		return nil, fmt.Errorf("rotation time must be positive, got %s", config.RotationTime)
+	}
 
 	writer, err := rotatelogs.New(
 		logName+"."+config.Format+suffix,
@@ -33,9 +50,9 @@ func NewWriterWithAge(logName string, opts ...SplitByAgeOption) io.Writer {
 		rotatelogs.WithRotationTime(config.RotationTime), // 每隔多久分割
 	)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("new rotate writer err: %w", err)
 	}
-	return writer
+	return writer, nil
 }
 
 type SplitBySizeConfig struct {
@@ -102,6 +119,8 @@ type SplitByAgeConfig struct {
 	Format       string        // 切割日期格式
 	MaxAge       time.Duration // 保留过期文件最大时间
 	RotationTime time.Duration // 每隔多久切割一次
+
+	err error // 选项中的错误，由 NewWriterWithAgeE 返回
 }
 
 type SplitByAgeOption func(*SplitByAgeConfig)
@@ -118,12 +137,22 @@ func SplitByAgeWithMaxAge(ma time.Duration) SplitByAgeOption {
 	}
 }
 
+// SplitByAgeWithRotationTime 设置切割间隔，rt 无法解析时 NewWriterWithAge panic，NewWriterWithAgeE 返回错误
+//
+//	eg: "24h"
 func SplitByAgeWithRotationTime(rt string) SplitByAgeOption {
 	return func(config *SplitByAgeConfig) {
 		duration, err := time.ParseDuration(rt)
 		if err != nil {
-			panic(err)
+			config.err = fmt.Errorf("parse rotation time err: %w", err)
+			return
 		}
 		config.RotationTime = duration
 	}
 }
+
+func SplitByAgeWithRotationDuration(rt time.Duration) SplitByAgeOption {
+	return func(config *SplitByAgeConfig) {
+		config.RotationTime = rt
+	}
+}
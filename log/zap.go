@@ -20,9 +20,17 @@ type Option func(*config)
 
 type config struct {
 	level         zapcore.Level
+	atomicLevel   *zap.AtomicLevel
 	encoder       Encoder
 	addCallerSkip int
+	disableCaller bool
+	stacktrace    zapcore.Level
 	writers       []io.Writer
+	sinks         []Sink
+	sampling      []samplingRule
+	samplingStats *SamplingStats
+	redactor      *redactor
+	extraCores    []func(level zap.AtomicLevel) zapcore.Core // 由其他文件中的 Option 添加的输出
 }
 
 func ConfigWithLevel(l zapcore.Level) Option {
@@ -31,6 +39,13 @@ func ConfigWithLevel(l zapcore.Level) Option {
 	}
 }
 
+// ConfigWithAtomicLevel 使用 al 控制日志级别，NewLogger 时会将 al 设置为 ConfigWithLevel 的级别，之后可以在运行时修改
+func ConfigWithAtomicLevel(al zap.AtomicLevel) Option {
+	return func(config *config) {
+		config.atomicLevel = &al
+	}
+}
+
 func ConfigWithEncoder(e Encoder) Option {
 	return func(config *config) {
 		config.encoder = e
@@ -43,24 +58,92 @@ func ConfigWithAddCallerSkip(acs int) Option {
 	}
 }
 
+// ConfigWithoutCaller 日志中不输出调用位置
+func ConfigWithoutCaller() Option {
+	return func(config *config) {
+		config.disableCaller = true
+	}
+}
+
+// ConfigWithStacktraceLevel 设置输出调用栈的最低级别，默认 Fatal
+func ConfigWithStacktraceLevel(l zapcore.Level) Option {
+	return func(config *config) {
+		config.stacktrace = l
+	}
+}
+
 func ConfigWithWriters(ws []io.Writer) Option {
 	return func(config *config) {
 		config.writers = ws
 	}
 }
 
+// ConfigWithSinks 添加独立的输出，每个输出有自己的 writer、编码和级别范围
+//
+//	eg: 全部日志以 console 格式输出到标准输出，Error 及以上的日志以 json 格式额外写入 error.log
+//	ConfigWithSinks(
+//		NewSink(os.Stdout),
+//		NewSink(NewWriterWithAge("./error.log"), SinkWithEncoder(EncoderJson), SinkWithMinLevel(zapcore.ErrorLevel)),
+//	)
+//	只设置了 sinks 时不再默认输出到标准输出
+func ConfigWithSinks(sinks ...Sink) Option {
+	return func(config *config) {
+		config.sinks = append(config.sinks, sinks...)
+	}
+}
+
 func NewLogger(opts ...Option) *zap.SugaredLogger {
 	config := &config{
 		level:         zapcore.DebugLevel,
 		encoder:       EncoderConsole,
 		addCallerSkip: 1,
-		writers:       []io.Writer{os.Stdout},
+		stacktrace:    zapcore.FatalLevel,
 	}
 
 	for _, opt := range opts {
 		opt(config)
 	}
+	if len(config.writers) == 0 && len(config.sinks) == 0 && len(config.extraCores) == 0 {
+		config.writers = []io.Writer{os.Stdout}
+	}
+
+	level := zap.NewAtomicLevel()
+	if config.atomicLevel != nil {
+		level = *config.atomicLevel
+	}
+	level.SetLevel(config.level)
 
+	zapOpts := []zap.Option{
+		zap.WithCaller(!config.disableCaller),
+		zap.Development(),
+		zap.AddStacktrace(config.stacktrace),
+		zap.AddCallerSkip(config.addCallerSkip),
+	}
+
+	var cores []zapcore.Core
+	if len(config.writers) > 0 {
+		var ws []zapcore.WriteSyncer
+		for _, writer := range config.writers {
+			ws = append(ws, zapcore.AddSync(writer))
+		}
+		cores = append(cores, zapcore.NewCore(newEncoder(config.encoder), zapcore.NewMultiWriteSyncer(ws...), level))
+	}
+	for _, sink := range config.sinks {
+		cores = append(cores, sink.core(config.encoder, level))
+	}
+	for _, newCore := range config.extraCores {
+		cores = append(cores, newCore(level))
+	}
+	for i := range cores {
+		cores[i] = newRedactCore(cores[i], config.redactor)
+	}
+
+	core := newSamplingCore(zapcore.NewTee(cores...), config.sampling, config.samplingStats)
+	return zap.New(core, zapOpts...).Sugar()
+}
+
+// newEncoder 创建日志编码器，所有输出使用相同的字段布局
+func newEncoder(e Encoder) zapcore.Encoder {
 	encoderConfig := zapcore.EncoderConfig{
 		MessageKey:     "M",
 		LevelKey:       "L",
@@ -81,37 +164,8 @@ func NewLogger(opts ...Option) *zap.SugaredLogger {
 		ConsoleSeparator: "  ",
 	}
 
-	var encoding zapcore.Encoder
-	if config.encoder == EncoderConsole {
-		encoding = zapcore.NewConsoleEncoder(encoderConfig)
-	} else {
-		encoding = zapcore.NewJSONEncoder(encoderConfig)
+	if e == EncoderConsole {
+		return zapcore.NewConsoleEncoder(encoderConfig)
 	}
-
-	zapOpts := []zap.Option{
-		zap.AddCaller(),
-		zap.Development(),
-		zap.AddStacktrace(zapcore.FatalLevel),
-		zap.AddCallerSkip(config.addCallerSkip),
-	}
-
-	var ws []zapcore.WriteSyncer
-	for _, writer := range config.writers {
-		ws = append(ws, zapcore.AddSync(writer))
-	}
-
-	return zap.New(zapcore.NewTee(
-		// zapcore.NewCore(encoding, zapcore.AddSync(utils.GetWriterWithAge("./tmp/debug.log")), zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-		// 	return l == zapcore.DebugLevel
-		// })),
-		// zapcore.NewCore(encoding, zapcore.AddSync(utils.GetWriterWithAge("./tmp/info.log")), zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-		// 	return l >= zapcore.InfoLevel
-		// })),
-		// zapcore.NewCore(encoding, zapcore.AddSync(utils.GetWriterWithAge("./tmp/error.log")), zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-		// 	return l >= zapcore.ErrorLevel
-		// })),
-		zapcore.NewCore(encoding, zapcore.NewMultiWriteSyncer(ws...), zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-			return l >= config.level
-		})),
-	), zapOpts...).Sugar()
+	return zapcore.NewJSONEncoder(encoderConfig)
 }
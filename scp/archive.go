@@ -0,0 +1,420 @@
+package scp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// ScpArchive 将本地目录打包为 tar 流，通过 ssh 会话推送到远端并由远端的 tar 解压到 remoteDir
+//
+//	适合大量小文件的场景，远端需要有 tar 命令，使用压缩时还需要对应的 gzip/zstd 命令
+//	WithCompression 设置压缩算法，WithInclude/WithExclude 过滤文件
+//	默认由远端用户持有文件且不保留修改时间，WithPreserve 时保留属主(需要 root)、权限和时间
+func (c *Client) ScpArchive(localDir, remoteDir string, opts ...Option) error {
+	return c.ScpArchiveContext(context.Background(), localDir, remoteDir, opts...)
+}
+
+// ScpArchiveContext 同 ScpArchive，ctx 取消时中止传输
+func (c *Client) ScpArchiveContext(ctx context.Context, localDir, remoteDir string, opts ...Option) error {
+	o := c.options(opts)
+
+	entries, total, err := archiveEntries(localDir, o)
+	if err != nil {
+		return err
+	}
+
+	flags := "--no-same-owner -m"
+	if o.preserve {
+		flags = "--same-owner -p"
+	}
+	cmd := fmt.Sprintf("mkdir -p %s && tar -x%s -f - %s -C %s",
+		shellQuote(remoteDir), tarCompressFlag(o.compression), flags, shellQuote(remoteDir))
+
+	pr, pw := io.Pipe()
+	bar := o.progress(total, fmt.Sprintf("scp archive %s to %s", localDir, remoteDir))
+	done := make(chan error, 1)
+	go func() {
+		err := writeArchive(ctx, pw, localDir, entries, o, bar)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	err = c.run(ctx, cmd, pr, nil)
+	pr.CloseWithError(io.ErrClosedPipe)
+	if writeErr := <-done; writeErr != nil && writeErr != io.ErrClosedPipe {
+		return fmt.Errorf("scp archive %s err: %w", localDir, cause(ctx, writeErr))
+	}
+	return err
+}
+
+// PullArchive 在远端将 remoteDir 打包为 tar 流，通过 ssh 会话拉取并解压到本地目录 localDir
+//
+//	过滤规则在本地解压时生效，被排除的文件仍然会经过网络传输
+func (c *Client) PullArchive(remoteDir, localDir string, opts ...Option) error {
+	return c.PullArchiveContext(context.Background(), remoteDir, localDir, opts...)
+}
+
+// PullArchiveContext 同 PullArchive，ctx 取消时中止传输
+func (c *Client) PullArchiveContext(ctx context.Context, remoteDir, localDir string, opts ...Option) error {
+	o := c.options(opts)
+
+	cmd := fmt.Sprintf("tar -c%s -f - -C %s .", tarCompressFlag(o.compression), shellQuote(remoteDir))
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := c.run(ctx, cmd, nil, pw)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	bar := o.progress(-1, fmt.Sprintf("pull archive %s to %s", remoteDir, localDir))
+	err := extractArchive(ctx, io.TeeReader(pr, bar), localDir, o)
+	if err == nil {
+		// tar 结束标记之后还有补齐的空块，读完才能让远端正常退出
+		_, err = io.Copy(io.Discard, pr)
+	}
+	pr.CloseWithError(io.ErrClosedPipe)
+	if runErr := <-done; runErr != nil && runErr != io.ErrClosedPipe {
+		return runErr
+	}
+	if err != nil {
+		return fmt.Errorf("pull archive %s err: %w", remoteDir, cause(ctx, err))
+	}
+	return nil
+}
+
+// archiveEntry 需要打包的本地条目
+type archiveEntry struct {
+	rel  string
+	info os.FileInfo
+}
+
+// archiveEntries 遍历本地目录，返回需要打包的目录、普通文件和符号链接，以及普通文件的总大小
+func archiveEntries(root string, o *options) ([]archiveEntry, int64, error) {
+	var entries []archiveEntry
+	var total int64
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+
+		switch {
+		case info.IsDir():
+			if o.excluded(rel) {
+				return filepath.SkipDir
+			}
+		case info.Mode().IsRegular(), info.Mode()&os.ModeSymlink != 0:
+			if !o.included(rel) {
+				return nil
+			}
+			if info.Mode().IsRegular() {
+				total += info.Size()
+			}
+		default:
+			return nil
+		}
+		entries = append(entries, archiveEntry{rel: rel, info: info})
+		return nil
+	})
+	return entries, total, err
+}
+
+func writeArchive(ctx context.Context, w io.Writer, root string, entries []archiveEntry, o *options, bar io.Writer) error {
+	cw, err := compressWriter(w, o.compression)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(cw)
+
+	for _, entry := range entries {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		local := filepath.Join(root, filepath.FromSlash(entry.rel))
+
+		var link string
+		if entry.info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(local); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(entry.info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = entry.rel
+		if entry.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if !o.preserve {
+			hdr.Uid, hdr.Gid = 0, 0
+			hdr.Uname, hdr.Gname = "", ""
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if entry.info.Mode().IsRegular() {
+			if err = copyFile(ctx, tw, local, o.limiter, bar); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+func copyFile(ctx context.Context, w io.Writer, name string, l *limiter, bar io.Writer) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, io.TeeReader(newCtxReader(ctx, f, l), bar))
+	return err
+}
+
+// extractArchive 将 tar 流解压到本地目录，拒绝解压到目录之外的条目
+//
+//	拒绝经过符号链接写入的条目，默认拒绝绝对路径或指向目录之外的符号链接
+func extractArchive(ctx context.Context, r io.Reader, root string, o *options) error {
+	dr, err := decompressReader(newCtxReader(ctx, r, o.limiter), o.compression)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+	tr := tar.NewReader(dr)
+
+	// 目录的权限和时间在所有文件解压之后设置，避免被子条目修改或无法写入
+	dirs := make(map[string]*tar.Header)
+	var skipped []string
+next:
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rel := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if rel == "." {
+			continue
+		}
+		if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("illegal path in archive: %s", hdr.Name)
+		}
+		for _, dir := range skipped {
+			if strings.HasPrefix(rel, dir+"/") {
+				continue next
+			}
+		}
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		if err = checkParents(root, rel); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if o.excluded(rel) {
+				skipped = append(skipped, rel)
+				continue
+			}
+			if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("illegal path in archive: %s is a symlink", hdr.Name)
+			}
+			if err = os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			dirs[target] = hdr
+		case tar.TypeReg:
+			if !o.included(rel) {
+				continue
+			}
+			if err = extractFile(tr, target, hdr, o); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if !o.included(rel) {
+				continue
+			}
+			if !o.unsafeLinks {
+				link := path.Join(path.Dir(rel), hdr.Linkname)
+				if path.IsAbs(hdr.Linkname) || link == ".." || strings.HasPrefix(link, "../") {
+					return fmt.Errorf("illegal symlink in archive: %s -> %s", hdr.Name, hdr.Linkname)
+				}
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err = os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err = os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+			if o.preserve {
+				if err = os.Lchown(target, hdr.Uid, hdr.Gid); err != nil && !isPermission(err) {
+					return err
+				}
+			}
+		}
+	}
+
+	// 逆序设置，保证子目录先于父目录
+	var names []string
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		if err = setHeaderMeta(name, dirs[name], o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, hdr *tar.Header, o *options) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// 已存在的符号链接先删除，避免写入链接指向的文件
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err = os.Remove(target); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return setHeaderMeta(target, hdr, o)
+}
+
+// checkParents 检查 rel 在 root 下的每一级父目录，拒绝其中的符号链接，不存在的目录由调用方创建
+func checkParents(root, rel string) error {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := path.Join(parts[:i]...)
+		info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(parent)))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("illegal path in archive: %s is under symlink %s", rel, parent)
+		}
+	}
+	return nil
+}
+
+// setHeaderMeta 按 tar 头设置权限，WithPreserve 时同时设置属主和时间
+func setHeaderMeta(target string, hdr *tar.Header, o *options) error {
+	mode := hdr.FileInfo().Mode().Perm()
+	if !o.preserve {
+		return os.Chmod(target, mode)
+	}
+	atime := hdr.AccessTime
+	if atime.IsZero() {
+		atime = hdr.ModTime
+	}
+	return setLocalMeta(target, fileMeta{
+		mode:  mode,
+		atime: atime,
+		mtime: hdr.ModTime,
+		uid:   hdr.Uid,
+		gid:   hdr.Gid,
+	})
+}
+
+// nopWriteCloser 不压缩时使用，Close 不关闭底层的 writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func compressWriter(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", c)
+	}
+}
+
+func decompressReader(r io.Reader, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", c)
+	}
+}
+
+// tarCompressFlag 远端 tar 压缩或解压时使用的参数
+//
+//	由 tar 自己调用压缩程序，而不是通过管道连接，保证 tar 失败时命令的退出码不为 0
+func tarCompressFlag(c Compression) string {
+	switch c {
+	case CompressionGzip:
+		return " -z"
+	case CompressionZstd:
+		return " -I zstd"
+	default:
+		return ""
+	}
+}
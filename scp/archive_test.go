@@ -0,0 +1,172 @@
+package scp
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScpArchive(t *testing.T) {
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not found")
+	}
+	client := newTestClient(t, WithoutProgress())
+
+	for _, compression := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		if compression != CompressionNone {
+			if _, err := exec.LookPath(string(compression)); err != nil {
+				t.Logf("%s not found, skip", compression)
+				continue
+			}
+		}
+		t.Run(string(compression)+"-", func(t *testing.T) {
+			localDir := t.TempDir()
+			remoteDir := filepath.Join(t.TempDir(), "dst")
+			writeTree(t, localDir, map[string]string{
+				"a.conf":          "a",
+				"sub/b.conf":      "b",
+				"sub/c.tmp":       "c",
+				"cache/d.conf":    "d",
+				"sub/deep/e.conf": "e",
+			})
+			if err := os.Symlink("a.conf", filepath.Join(localDir, "link.conf")); err != nil {
+				t.Fatal(err)
+			}
+			mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+			if err := os.Chtimes(filepath.Join(localDir, "a.conf"), mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			err := client.ScpArchive(localDir, remoteDir,
+				WithCompression(compression), WithExclude("*.tmp", "cache"), WithPreserve())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertFile(t, filepath.Join(remoteDir, "a.conf"), []byte("a"))
+			assertFile(t, filepath.Join(remoteDir, "sub/deep/e.conf"), []byte("e"))
+			for _, name := range []string{"sub/c.tmp", "cache"} {
+				if _, err := os.Stat(filepath.Join(remoteDir, name)); !os.IsNotExist(err) {
+					t.Errorf("%s was not excluded: %v", name, err)
+				}
+			}
+			if link, err := os.Readlink(filepath.Join(remoteDir, "link.conf")); err != nil || link != "a.conf" {
+				t.Errorf("link.conf = %q, %v", link, err)
+			}
+			if info, err := os.Stat(filepath.Join(remoteDir, "a.conf")); err != nil || !info.ModTime().Equal(mtime) {
+				t.Errorf("a.conf mtime = %v, %v", info.ModTime(), err)
+			}
+
+			pulled := t.TempDir()
+			err = client.PullArchive(remoteDir, pulled,
+				WithCompression(compression), WithExclude("deep"), WithPreserve())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertFile(t, filepath.Join(pulled, "a.conf"), []byte("a"))
+			assertFile(t, filepath.Join(pulled, "sub/b.conf"), []byte("b"))
+			if _, err := os.Stat(filepath.Join(pulled, "sub/deep")); !os.IsNotExist(err) {
+				t.Errorf("sub/deep was not excluded: %v", err)
+			}
+			if info, err := os.Stat(filepath.Join(pulled, "a.conf")); err != nil || !info.ModTime().Equal(mtime) {
+				t.Errorf("pulled a.conf mtime = %v, %v", info.ModTime(), err)
+			}
+		})
+	}
+}
+
+func TestPullArchiveMissing(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+
+	for _, compression := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		if compression != CompressionNone {
+			if _, err := exec.LookPath(string(compression)); err != nil {
+				t.Logf("%s not found, skip", compression)
+				continue
+			}
+		}
+		err := client.PullArchive(filepath.Join(t.TempDir(), "missing"), t.TempDir(), WithCompression(compression))
+		if err == nil {
+			t.Fatalf("%s: want error for missing remote directory", compression)
+		}
+	}
+}
+
+func TestExtractArchiveSymlink(t *testing.T) {
+	tarball := func(t *testing.T, hdrs ...*tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range hdrs {
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Size > 0 {
+				tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size)))
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+
+	outside := t.TempDir()
+	tests := []struct {
+		name string
+		hdrs []*tar.Header
+		want string
+		opts []Option
+	}{
+		{"through symlink", []*tar.Header{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "link/pwned", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		}, "under symlink link", []Option{WithUnsafeLinks()}},
+		{"dir through symlink", []*tar.Header{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "link/", Typeflag: tar.TypeDir, Mode: 0o711},
+		}, "is a symlink", []Option{WithUnsafeLinks()}},
+		{"absolute link", []*tar.Header{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+		}, "illegal symlink", nil},
+		{"relative escape", []*tar.Header{
+			{Name: "sub/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+		}, "illegal symlink", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			err := extractArchive(context.Background(), tarball(t, tt.hdrs...), root, new(Client).options(tt.opts))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(outside, "pwned")); !os.IsNotExist(err) {
+				t.Fatalf("wrote outside root: %v", err)
+			}
+			if info, err := os.Stat(outside); err != nil || info.Mode().Perm() == 0o711 {
+				t.Fatalf("outside dir modified: %v", err)
+			}
+		})
+	}
+
+	// 目录之内的链接和已存在的链接目标不受影响
+	root := t.TempDir()
+	if err := os.Symlink(filepath.Join(outside, "victim"), filepath.Join(root, "a.conf")); err != nil {
+		t.Fatal(err)
+	}
+	err := extractArchive(context.Background(), tarball(t,
+		&tar.Header{Name: "sub/link", Typeflag: tar.TypeSymlink, Linkname: "../a.conf"},
+		&tar.Header{Name: "a.conf", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+	), root, new(Client).options(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(root, "a.conf"), []byte("x"))
+	if _, err := os.Stat(filepath.Join(outside, "victim")); !os.IsNotExist(err) {
+		t.Fatalf("wrote through existing symlink: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// run 在远端执行命令，stdin/stdout 可以为 nil，ctx 取消时关闭会话
+func (c *Client) run(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	if c.conn == nil {
+		return errors.New("run command err: client has no ssh connection")
+	}
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	defer closeOnCancel(ctx, session)()
+
+	var stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = &stderr
+	if err = session.Run(cmd); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("run %q err: %w: %s", cmd, err, msg)
+		}
+		return fmt.Errorf("run %q err: %w", cmd, err)
+	}
+	return nil
+}
+
+// shellQuote 使用单引号转义，使参数可以安全地拼接到远端 shell 命令中
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,152 @@
+package scp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// bufferCount 流式复制时使用的缓冲区个数，内存占用上限为 bufferCount * bufferSize
+const bufferCount = 4
+
+// Copy 将 src 主机上的 srcPath 复制到 dst 主机上的 dstPath，数据经本地内存中转，不落盘
+//
+//	读写分别在两个协程中进行，最多缓存 4 个 WithBufferSize 大小的数据块
+//	WithDirect 时先尝试让源主机直接推送到目标主机，WithPreserve 保留源文件的元数据
+//	出错时删除目标主机上不完整的文件
+func Copy(src *Client, srcPath string, dst *Client, dstPath string, opts ...Option) error {
+	return CopyContext(context.Background(), src, srcPath, dst, dstPath, opts...)
+}
+
+// CopyContext 同 Copy，ctx 取消时中止复制并删除目标主机上不完整的文件
+func CopyContext(ctx context.Context, src *Client, srcPath string, dst *Client, dstPath string, opts ...Option) (err error) {
+	o := dst.options(opts)
+
+	if o.direct {
+		if err = src.pushDirect(ctx, srcPath, dst, dstPath, o); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	srcFile, err := src.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	defer closeOnCancel(ctx, srcFile)()
+
+	info, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	dstFile, err := dst.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+	defer func() {
+		if err != nil {
+			dst.Remove(dstPath)
+		}
+	}()
+	defer closeOnCancel(ctx, dstFile)()
+
+	bar := o.progress(info.Size(), fmt.Sprintf("copy %s:%s to %s:%s", src.addr, srcPath, dst.addr, dstPath))
+	r := io.TeeReader(newCtxReader(ctx, srcFile, o.limiter), bar)
+	if err = bufferedCopy(dstFile, r, o.bufferSize, bufferCount); err != nil {
+		return fmt.Errorf("copy %s to %s err: %w", srcPath, dstPath, cause(ctx, err))
+	}
+	if err = dstFile.Close(); err != nil {
+		return err
+	}
+
+	if o.preserve {
+		return dst.setRemoteMeta(dstPath, remoteMeta(info))
+	}
+	return nil
+}
+
+// pushDirect 在源主机上执行 scp，将文件直接推送到目标主机
+func (c *Client) pushDirect(ctx context.Context, srcPath string, dst *Client, dstPath string, o *options) error {
+	host, port, err := net.SplitHostPort(dst.addr)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	args := []string{
+		"scp", "-q", "-P", port,
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+	}
+	if o.preserve {
+		args = append(args, "-p")
+	}
+	if o.limiter != nil {
+		// scp -l 的单位是 Kbit/s
+		args = append(args, "-l", fmt.Sprintf("%d", int64(o.limiter.rate)*8/1000+1))
+	}
+	args = append(args, shellQuote(srcPath), shellQuote(fmt.Sprintf("%s@%s:%s", dst.user, host, dstPath)))
+	return c.run(ctx, strings.Join(args, " "), nil, nil)
+}
+
+// bufferedCopy 读写分离的复制，读协程最多领先写入 count 个缓冲区
+func bufferedCopy(w io.Writer, r io.Reader, size, count int) error {
+	type block struct {
+		buf []byte
+		n   int
+	}
+	free := make(chan []byte, count)
+	for i := 0; i < count; i++ {
+		free <- make([]byte, size)
+	}
+	full := make(chan block, count)
+	stop := make(chan struct{})
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(full)
+		for {
+			var buf []byte
+			select {
+			case buf = <-free:
+			case <-stop:
+				return
+			}
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				select {
+				case full <- block{buf: buf, n: n}:
+				case <-stop:
+					return
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				readErr <- nil
+				return
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for b := range full {
+		if _, err := w.Write(b.buf[:b.n]); err != nil {
+			close(stop)
+			return err
+		}
+		free <- b.buf
+	}
+	return <-readErr
+}
@@ -0,0 +1,69 @@
+package scp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	src := newTestClient(t, WithoutProgress())
+	dst := newTestClient(t, WithoutProgress())
+
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.bin")
+	data := randomFile(t, srcPath, 1<<20+123)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(srcPath, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	dstPath := filepath.Join(dir, "dst.bin")
+	if err := Copy(src, srcPath, dst, dstPath, WithBufferSize(64<<10), WithPreserve()); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, dstPath, data)
+	if info, err := os.Stat(dstPath); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, %v", info.ModTime(), err)
+	}
+
+	// 测试服务只支持密码登录，直接推送失败后回退到本地中转
+	direct := filepath.Join(dir, "direct.bin")
+	if err := Copy(src, srcPath, dst, direct, WithDirect()); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, direct, data)
+}
+
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n -= len(p); w.n < 0 {
+		return 0, errors.New("write failed")
+	}
+	return len(p), nil
+}
+
+func TestBufferedCopy(t *testing.T) {
+	data := make([]byte, 100000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	if err := bufferedCopy(&buf, bytes.NewReader(data), 1000, 3); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("content mismatch")
+	}
+
+	if err := bufferedCopy(&failWriter{n: 5000}, bytes.NewReader(data), 1000, 3); err == nil {
+		t.Error("want write error")
+	}
+}
@@ -0,0 +1,198 @@
+package scp
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// 加密文件的格式：
+//
+//	header: magic "PKE1" | 分段大小(4 字节，大端) | nonce 前缀(7 字节)
+//	之后是按分段大小切分的明文经 AES-GCM 加密后的密文，每段带 16 字节的 tag
+//	nonce = nonce 前缀 | 分段序号(4 字节，大端) | 是否为最后一段(1 字节)，最后一段的标记可以发现被截断的文件
+const (
+	encMagic       = "PKE1"
+	encHeaderSize  = 4 + 4 + 7
+	encSegmentSize = 64 << 10
+	encMaxSegment  = 16 << 20
+)
+
+// ErrDecrypt 解密失败，密钥错误或远端文件被篡改、截断
+var ErrDecrypt = errors.New("decrypt failed")
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key err: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedSize 返回 n 字节明文加密后的大小
+func encryptedSize(n int64) int64 {
+	segments := (n + encSegmentSize - 1) / encSegmentSize
+	if segments == 0 {
+		segments = 1
+	}
+	return encHeaderSize + n + segments*16
+}
+
+// storedSize 返回 n 字节的本地内容上传后在远端的大小，设置了密钥时为密文大小
+func storedSize(n int64, o *options) int64 {
+	if o.encryptionKey != nil {
+		return encryptedSize(n)
+	}
+	return n
+}
+
+// segmentNonce 生成分段的 nonce
+func segmentNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[7:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// readSegment 读取一个分段，通过预读一个字节判断是否为最后一段
+func readSegment(r *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		return n, true, nil
+	case nil:
+		if _, err = r.Peek(1); err == io.EOF {
+			return n, true, nil
+		}
+		return n, false, err
+	default:
+		return n, false, err
+	}
+}
+
+// encryptReader 读取明文，输出加密后的内容
+type encryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	plain   []byte
+	out     []byte
+	buf     []byte // 尚未读走的密文
+	done    bool
+}
+
+func newEncryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, encHeaderSize)
+	copy(header, encMagic)
+	binary.BigEndian.PutUint32(header[4:], encSegmentSize)
+	if _, err = rand.Read(header[8:]); err != nil {
+		return nil, err
+	}
+	return &encryptReader{
+		r:      bufio.NewReaderSize(r, encSegmentSize),
+		aead:   aead,
+		prefix: header[8:],
+		plain:  make([]byte, encSegmentSize),
+		out:    make([]byte, 0, encSegmentSize+aead.Overhead()),
+		buf:    header,
+	}, nil
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.buf) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		n, last, err := readSegment(e.r, e.plain)
+		if err != nil {
+			return 0, err
+		}
+		if e.counter == math.MaxUint32 && !last {
+			return 0, errors.New("encrypt err: file too large")
+		}
+		e.buf = e.aead.Seal(e.out[:0], segmentNonce(e.prefix, e.counter, last), e.plain[:n], nil)
+		e.counter++
+		e.done = last
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+// decryptReader 读取加密后的内容，输出明文
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	sealed  []byte
+	out     []byte
+	buf     []byte // 尚未读走的明文
+	done    bool
+}
+
+func newDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: bufio.NewReaderSize(r, encSegmentSize), aead: aead}, nil
+}
+
+// readHeader 读取并校验文件头
+func (d *decryptReader) readHeader() error {
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: file is not encrypted", ErrDecrypt)
+		}
+		return err
+	}
+	size := binary.BigEndian.Uint32(header[4:])
+	if string(header[:4]) != encMagic || size == 0 || size > encMaxSegment {
+		return fmt.Errorf("%w: file is not encrypted", ErrDecrypt)
+	}
+	d.prefix = header[8:]
+	d.sealed = make([]byte, int(size)+d.aead.Overhead())
+	d.out = make([]byte, 0, size)
+	return nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.sealed == nil {
+		if err := d.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		n, last, err := readSegment(d.r, d.sealed)
+		if err != nil {
+			return 0, err
+		}
+		if d.buf, err = d.aead.Open(d.out[:0], segmentNonce(d.prefix, d.counter, last), d.sealed[:n], nil); err != nil {
+			return 0, fmt.Errorf("%w: segment %d", ErrDecrypt, d.counter)
+		}
+		d.counter++
+		d.done = last
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,134 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func encrypt(t *testing.T, data, key []byte) []byte {
+	t.Helper()
+
+	r, err := newEncryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func decrypt(data, key []byte) ([]byte, error) {
+	r, err := newDecryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptReader(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	for _, size := range []int{0, 1, encSegmentSize - 1, encSegmentSize, encSegmentSize + 1, 3 * encSegmentSize} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		sealed := encrypt(t, data, key)
+		if int64(len(sealed)) != encryptedSize(int64(size)) {
+			t.Fatalf("size %d: encrypted %d bytes, want %d", size, len(sealed), encryptedSize(int64(size)))
+		}
+		got, err := decrypt(sealed, key)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("size %d: content mismatch", size)
+		}
+	}
+}
+
+func TestDecryptReaderErrors(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	data := make([]byte, 2*encSegmentSize+100)
+	sealed := encrypt(t, data, key)
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[encHeaderSize+10] ^= 1
+
+	tests := map[string][]byte{
+		"wrong key":     nil,
+		"tampered":      tampered,
+		"truncated":     sealed[:encHeaderSize+encSegmentSize+16],
+		"not encrypted": []byte("plain text"),
+		"empty":         {},
+	}
+	for name, input := range tests {
+		decryptKey := key
+		if input == nil {
+			input, decryptKey = sealed, bytes.Repeat([]byte{2}, 32)
+		}
+		if _, err := decrypt(input, decryptKey); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: err = %v, want ErrDecrypt", name, err)
+		}
+	}
+
+	if _, err := newEncryptReader(bytes.NewReader(data), []byte("short")); err == nil {
+		t.Error("invalid key size accepted")
+	}
+}
+
+func TestScpEncrypted(t *testing.T) {
+	client := newMemClient(t, WithoutProgress(), WithChunkSize(1000))
+	key := bytes.Repeat([]byte{7}, 16)
+	data := bytes.Repeat([]byte("secret "), 20000)
+	fsys := fstest.MapFS{"secret.txt": {Data: data}}
+
+	if err := client.Scp("secret.txt", "/secret.txt", WithLocalFS(fsys), WithEncryptionKey(key)); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := client.DownloadBytes("/secret.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(stored)) != encryptedSize(int64(len(data))) || bytes.Contains(stored, []byte("secret")) {
+		t.Fatal("remote file is not encrypted")
+	}
+
+	local := filepath.Join(t.TempDir(), "secret.txt")
+	if err = client.Pull("/secret.txt", local, WithEncryptionKey(key)); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, local, data)
+
+	if err = client.Pull("/secret.txt", local, WithEncryptionKey(bytes.Repeat([]byte{8}, 16))); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestUploadEncrypted(t *testing.T) {
+	client := newMemClient(t, WithoutProgress(), WithEncryptionKey(bytes.Repeat([]byte{7}, 32)))
+	ctx := context.Background()
+
+	if err := client.UploadBytesContext(ctx, []byte("token=abc"), "/token"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.DownloadBytesContext(ctx, "/token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "token=abc" {
+		t.Fatalf("got %q", got)
+	}
+	if got, err = client.DownloadBytesContext(ctx, "/token", WithEncryptionKey(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(got, []byte("token")) {
+		t.Fatal("remote file is not encrypted")
+	}
+}
@@ -0,0 +1,173 @@
+package scp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrDistributeAborted 金丝雀主机失败或失败主机数达到阈值后，剩余主机不再分发
+var ErrDistributeAborted = errors.New("distribute aborted")
+
+// Host 分发的目标主机
+type Host struct {
+	Addr     string // host:port
+	User     string
+	Password string
+}
+
+// HostResult 单台主机的分发结果
+type HostResult struct {
+	Addr     string
+	Manifest *Manifest // 连接失败或未分发时为 nil
+	Duration time.Duration
+	Err      error // 未分发的主机为 ErrDistributeAborted 或 ctx.Err()
+}
+
+// Distribute 将同一批本地文件推送到多台主机，返回与 hosts 顺序一致的每台主机的结果
+//
+//	WithHostConcurrency 控制同时分发的主机数，其他选项作用于每台主机的 ScpFiles
+//	WithCanary 先依次分发前 n 台主机，任意一台失败时不再分发其余主机
+//	WithFailureThreshold 失败的主机数达到阈值后不再分发新的主机，正在分发的主机会继续完成
+//	默认显示主机数的进度条，WithProgressFunc 时改为回调每台主机的传输字节数，name 为主机地址
+//	中止分发时返回的错误可以通过 errors.Is 同时匹配 ErrDistributeAborted 和第一个失败主机的错误
+func Distribute(hosts []Host, files []FilePair, opts ...Option) ([]HostResult, error) {
+	return DistributeContext(context.Background(), hosts, files, opts...)
+}
+
+// DistributeContext 同 Distribute，ctx 取消时中止正在分发的主机，不再分发其余主机
+func DistributeContext(ctx context.Context, hosts []Host, files []FilePair, opts ...Option) ([]HostResult, error) {
+	o := new(Client).options(opts)
+
+	var bar *progressbar.ProgressBar
+	if o.showProgress && o.progressFunc == nil {
+		bar = progressbar.Default(int64(len(hosts)), fmt.Sprintf("distribute %d files", len(files)))
+	}
+
+	results := make([]HostResult, len(hosts))
+	done := make([]bool, len(hosts))
+	var mu sync.Mutex
+	failed := 0
+	aborted := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return o.failureThreshold > 0 && failed >= o.failureThreshold
+	}
+	run := func(i int) {
+		res := distributeHost(ctx, hosts[i], files, opts, o)
+		mu.Lock()
+		results[i], done[i] = res, true
+		if res.Err != nil {
+			failed++
+		}
+		mu.Unlock()
+		if bar != nil {
+			bar.Add(1)
+		}
+	}
+
+	canary := o.canary
+	if canary > len(hosts) {
+		canary = len(hosts)
+	}
+	canaryFailed := false
+	for i := 0; i < canary && ctx.Err() == nil; i++ {
+		run(i)
+		if results[i].Err != nil {
+			canaryFailed = true
+			break
+		}
+	}
+
+	if !canaryFailed {
+		g := new(errgroup.Group)
+		g.SetLimit(o.hostConcurrency)
+		for i := canary; i < len(hosts); i++ {
+			i := i
+			if ctx.Err() != nil || aborted() {
+				break
+			}
+			g.Go(func() error {
+				if ctx.Err() == nil && !aborted() {
+					run(i)
+				}
+				return nil
+			})
+		}
+		g.Wait()
+	}
+
+	var first error
+	skipped := 0
+	for i := range results {
+		if !done[i] {
+			results[i] = HostResult{Addr: hosts[i].Addr, Err: cause(ctx, ErrDistributeAborted)}
+			skipped++
+			continue
+		}
+		if first == nil && results[i].Err != nil {
+			first = results[i].Err
+		}
+	}
+	switch {
+	case first == nil && skipped == 0:
+		return results, nil
+	case first == nil:
+		return results, cause(ctx, ErrDistributeAborted)
+	case skipped > 0:
+		return results, &abortedError{
+			msg:   fmt.Sprintf("%s: %d of %d hosts failed, %d skipped, first err: %v", ErrDistributeAborted, failed, len(hosts), skipped, first),
+			first: first,
+		}
+	case len(hosts) == 1:
+		return results, first
+	default:
+		return results, fmt.Errorf("%d of %d hosts failed, first err: %w", failed, len(hosts), first)
+	}
+}
+
+// abortedError 有主机失败且剩余主机未分发时返回，errors.Is 匹配 ErrDistributeAborted，Unwrap 返回第一个失败主机的错误
+type abortedError struct {
+	msg   string
+	first error
+}
+
+func (e *abortedError) Error() string { return e.msg }
+
+func (e *abortedError) Is(target error) bool { return target == ErrDistributeAborted }
+
+func (e *abortedError) Unwrap() error { return e.first }
+
+func distributeHost(ctx context.Context, host Host, files []FilePair, opts []Option, o *options) (res HostResult) {
+	start := time.Now()
+	res.Addr = host.Addr
+	defer func() {
+		res.Duration = time.Since(start)
+	}()
+
+	// 多台主机的进度条会相互覆盖，只保留回调
+	hostOpts := append(opts[:len(opts):len(opts)], WithoutProgress())
+	if fn := o.progressFunc; fn != nil {
+		hostOpts = append(hostOpts, WithProgressFunc(func(_ string, written, total int64) {
+			fn(host.Addr, written, total)
+		}))
+	}
+
+	client, err := NewClient(host.Addr, host.User, host.Password)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer client.Close()
+
+	res.Manifest, res.Err = client.ScpFilesContext(ctx, files, hostOpts...)
+	if res.Err != nil {
+		res.Err = fmt.Errorf("distribute to %s err: %w", host.Addr, res.Err)
+	}
+	return res
+}
@@ -0,0 +1,119 @@
+package scp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// newTestHosts 启动 n 台测试主机，返回主机列表和每台主机的根目录
+func newTestHosts(t *testing.T, n int) ([]Host, []string) {
+	t.Helper()
+
+	var hosts []Host
+	var dirs []string
+	for i := 0; i < n; i++ {
+		dir := t.TempDir()
+		hosts = append(hosts, Host{Addr: newTestServerDir(t, dir), User: testUser, Password: testPasswd})
+		dirs = append(dirs, dir)
+	}
+	return hosts, dirs
+}
+
+// unreachableHost 返回一个无法连接的主机
+func unreachableHost(t *testing.T) Host {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return Host{Addr: addr, User: testUser, Password: testPasswd}
+}
+
+func TestDistribute(t *testing.T) {
+	hosts, dirs := newTestHosts(t, 3)
+	local := filepath.Join(t.TempDir(), "app.bin")
+	data := randomFile(t, local, 1<<20)
+	files := []FilePair{{Local: local, Remote: "app.bin"}}
+
+	var mu sync.Mutex
+	progress := make(map[string]int64)
+	results, err := Distribute(hosts, files, WithHostConcurrency(2),
+		WithProgressFunc(func(name string, written, total int64) {
+			mu.Lock()
+			progress[name] = written
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, res := range results {
+		if res.Addr != hosts[i].Addr || res.Err != nil || len(res.Manifest.Failed()) != 0 {
+			t.Fatalf("host %d: %+v", i, res)
+		}
+		if progress[res.Addr] != int64(len(data)) {
+			t.Fatalf("host %s progress %d, want %d", res.Addr, progress[res.Addr], len(data))
+		}
+		assertFile(t, filepath.Join(dirs[i], "app.bin"), data)
+	}
+}
+
+func TestDistributeCanary(t *testing.T) {
+	good, dirs := newTestHosts(t, 2)
+	hosts := append([]Host{unreachableHost(t)}, good...)
+	local := filepath.Join(t.TempDir(), "app.bin")
+	randomFile(t, local, 1024)
+
+	results, err := Distribute(hosts, []FilePair{{Local: local, Remote: "app.bin"}},
+		WithCanary(1), WithoutProgress())
+	if !errors.Is(err, ErrDistributeAborted) {
+		t.Fatalf("err = %v, want ErrDistributeAborted", err)
+	}
+	if results[0].Err == nil || errors.Is(results[0].Err, ErrDistributeAborted) {
+		t.Fatalf("canary err = %v", results[0].Err)
+	}
+	if !errors.Is(err, results[0].Err) {
+		t.Fatalf("err = %v does not wrap canary err %v", err, results[0].Err)
+	}
+	for i, dir := range dirs {
+		if !errors.Is(results[i+1].Err, ErrDistributeAborted) {
+			t.Fatalf("host %d err = %v, want ErrDistributeAborted", i+1, results[i+1].Err)
+		}
+		if _, err = os.Stat(filepath.Join(dir, "app.bin")); !os.IsNotExist(err) {
+			t.Fatalf("host %d received file, err = %v", i+1, err)
+		}
+	}
+}
+
+func TestDistributeFailureThreshold(t *testing.T) {
+	good, dirs := newTestHosts(t, 2)
+	hosts := []Host{good[0], unreachableHost(t), unreachableHost(t), good[1]}
+	local := filepath.Join(t.TempDir(), "app.bin")
+	data := randomFile(t, local, 1024)
+
+	results, err := Distribute(hosts, []FilePair{{Local: local, Remote: "app.bin"}},
+		WithHostConcurrency(1), WithFailureThreshold(2), WithoutProgress())
+	if !errors.Is(err, ErrDistributeAborted) {
+		t.Fatalf("err = %v, want ErrDistributeAborted", err)
+	}
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	assertFile(t, filepath.Join(dirs[0], "app.bin"), data)
+	if results[1].Err == nil || results[2].Err == nil {
+		t.Fatalf("unreachable hosts succeeded: %+v", results[1:3])
+	}
+	if !errors.Is(results[3].Err, ErrDistributeAborted) {
+		t.Fatalf("last host err = %v, want ErrDistributeAborted", results[3].Err)
+	}
+	if _, err = os.Stat(filepath.Join(dirs[1], "app.bin")); !os.IsNotExist(err) {
+		t.Fatalf("last host received file, err = %v", err)
+	}
+}
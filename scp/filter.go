@@ -0,0 +1,68 @@
+package scp
+
+import (
+	"path"
+	"strings"
+)
+
+// match 判断斜杠分隔的相对路径 name 是否匹配 pattern
+//
+//	在 path.Match 的基础上支持 ** 匹配任意层目录，eg: "**/*.json", "conf/**"
+//	pattern 中不含 / 时只匹配文件名，eg: "*.log" 可以匹配 "a/b/c.log"
+func match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			if len(pat) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// excluded 判断相对路径是否被 exclude 规则排除，对文件和目录都生效
+func (o *options) excluded(rel string) bool {
+	for _, pattern := range o.excludes {
+		if match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// included 判断文件是否需要处理：未被排除，且 include 为空或命中任意一条 include 规则
+func (o *options) included(rel string) bool {
+	if o.excluded(rel) {
+		return false
+	}
+	if len(o.includes) == 0 {
+		return true
+	}
+	for _, pattern := range o.includes {
+		if match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
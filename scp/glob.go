@@ -0,0 +1,146 @@
+package scp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Find 在远端查找匹配 pattern 的普通文件，返回排序后的远端路径
+//
+//	pattern 语法同 path.Match，另外支持 ** 匹配任意层目录，eg: "/var/log/app/*.log", "/etc/app/**/*.json"
+//	不含 ** 时使用 sftp 的 Glob，否则从 pattern 中不含通配符的前缀目录开始遍历
+//	WithModifiedAfter/WithModifiedBefore/WithMinSize/WithMaxSize/WithExclude 过滤结果
+func (c *Client) Find(pattern string, opts ...Option) ([]string, error) {
+	o := c.options(opts)
+	base, rest := splitPattern(pattern)
+
+	var matches []string
+	if !strings.Contains(rest, "**") {
+		paths, err := c.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("glob %s err: %w", pattern, err)
+		}
+		for _, p := range paths {
+			info, err := c.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			if o.selected(relPath(base, p), info) {
+				matches = append(matches, p)
+			}
+		}
+	} else {
+		segments := strings.Split(rest, "/")
+		walker := c.Walk(base)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				if walker.Path() == base {
+					return nil, fmt.Errorf("glob %s err: %w", pattern, err)
+				}
+				continue
+			}
+			rel := relPath(base, walker.Path())
+			if walker.Stat().IsDir() {
+				if rel != "." && o.excluded(rel) {
+					walker.SkipDir()
+				}
+				continue
+			}
+			if matchSegments(segments, strings.Split(rel, "/")) && o.selected(rel, walker.Stat()) {
+				matches = append(matches, walker.Path())
+			}
+		}
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// PullGlob 拉取远端匹配 pattern 的文件到 localDir，保持文件相对于 pattern 前缀目录的层级，返回传输清单
+//
+//	eg: pattern "/var/log/app/**/*.log" 会将 /var/log/app/a/b.log 拉取到 localDir/a/b.log
+//	部分文件失败时同时返回清单和错误，清单可以用于 Retry
+func (c *Client) PullGlob(pattern, localDir string, opts ...Option) (*Manifest, error) {
+	return c.PullGlobContext(context.Background(), pattern, localDir, opts...)
+}
+
+// PullGlobContext 同 PullGlob，ctx 取消时中止传输并删除未传输完成的本地文件
+func (c *Client) PullGlobContext(ctx context.Context, pattern, localDir string, opts ...Option) (*Manifest, error) {
+	matches, err := c.Find(pattern, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	o := c.options(opts)
+	fsys, err := o.writeFS()
+	if err != nil {
+		return nil, err
+	}
+	base, _ := splitPattern(pattern)
+	files := make([]FilePair, 0, len(matches))
+	for _, remote := range matches {
+		local := filepath.Join(localDir, filepath.FromSlash(relPath(base, remote)))
+		if err = fsys.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+			return nil, err
+		}
+		files = append(files, FilePair{Local: local, Remote: remote})
+	}
+	states := c.downloadFiles(ctx, files, o, fmt.Sprintf("pull %s", pattern))
+	return newManifest(DirectionDownload, states, o.localFS), batchErr(states)
+}
+
+// selected 判断匹配到的条目是否满足类型、排除规则、修改时间和大小的过滤条件
+func (o *options) selected(rel string, info os.FileInfo) bool {
+	if !info.Mode().IsRegular() || o.excluded(rel) {
+		return false
+	}
+	if !o.modifiedAfter.IsZero() && !info.ModTime().After(o.modifiedAfter) {
+		return false
+	}
+	if !o.modifiedBefore.IsZero() && !info.ModTime().Before(o.modifiedBefore) {
+		return false
+	}
+	if info.Size() < o.minSize {
+		return false
+	}
+	return o.maxSize <= 0 || info.Size() <= o.maxSize
+}
+
+// splitPattern 将 pattern 拆分为不含通配符的前缀目录和剩余部分
+//
+//	eg: "/var/log/app/**/*.log" => "/var/log/app", "**/*.log"
+func splitPattern(pattern string) (base, rest string) {
+	segments := strings.Split(pattern, "/")
+	i := 0
+	for ; i < len(segments)-1; i++ {
+		if strings.ContainsAny(segments[i], `*?[\`) {
+			break
+		}
+	}
+	base = strings.Join(segments[:i], "/")
+	if base == "" {
+		if strings.HasPrefix(pattern, "/") {
+			base = "/"
+		} else {
+			base = "."
+		}
+	}
+	return base, strings.Join(segments[i:], "/")
+}
+
+// relPath 返回 p 相对于 base 的路径
+func relPath(base, p string) string {
+	if base == "." {
+		return path.Clean(p)
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(path.Clean(p), path.Clean(base)), "/")
+	if rel == "" {
+		return "."
+	}
+	return rel
+}
@@ -0,0 +1,94 @@
+package scp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"app/a.log":        "a",
+		"app/b.log":        "bbbb",
+		"app/c.txt":        "c",
+		"app/old/d.log":    "d",
+		"app/x/y/e.json":   "e",
+		"app/x/f.json":     "f",
+		"app/skip/g.json":  "g",
+		"app/x/y/h.jsonl":  "h",
+		"other/i.json":     "i",
+		"app/x/y/z/j.json": "j",
+	})
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "app/a.log"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern string
+		opts    []Option
+		want    []string
+	}{
+		{pattern: "app/*.log", want: []string{"app/a.log", "app/b.log"}},
+		{pattern: "app/*.log", opts: []Option{WithModifiedAfter(time.Now().Add(-time.Hour))}, want: []string{"app/b.log"}},
+		{pattern: "app/*.log", opts: []Option{WithMinSize(2)}, want: []string{"app/b.log"}},
+		{pattern: "app/*.log", opts: []Option{WithMaxSize(1)}, want: []string{"app/a.log"}},
+		{pattern: "app/**/*.json", opts: []Option{WithExclude("skip")}, want: []string{"app/x/f.json", "app/x/y/e.json", "app/x/y/z/j.json"}},
+		{pattern: "app/x/**/e.json", want: []string{"app/x/y/e.json"}},
+		{pattern: "app/nothing/*.log", want: nil},
+	}
+	for _, tt := range tests {
+		got, err := client.Find(filepath.Join(root, tt.pattern), tt.opts...)
+		if err != nil {
+			t.Fatalf("Find(%s) err: %v", tt.pattern, err)
+		}
+		var want []string
+		for _, name := range tt.want {
+			want = append(want, filepath.Join(root, name))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Find(%s) = %v, want %v", tt.pattern, got, want)
+		}
+	}
+}
+
+func TestPullGlob(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+
+	root, localDir := t.TempDir(), t.TempDir()
+	writeTree(t, root, map[string]string{
+		"log/a.log":     "a",
+		"log/sub/b.log": "b",
+		"log/c.txt":     "c",
+	})
+
+	// 本地已经存在同名目录的文件拉取失败，其余文件正常拉取
+	if err := os.MkdirAll(filepath.Join(localDir, "sub", "b.log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m, err := client.PullGlob(filepath.Join(root, "log/**/*.log"), localDir)
+	if err == nil {
+		t.Fatal("want error for directory in the way")
+	}
+	if len(m.Entries) != 2 || len(m.Failed()) != 1 || m.Failed()[0].Remote != filepath.Join(root, "log/sub/b.log") {
+		t.Fatalf("manifest = %+v", m)
+	}
+	assertFile(t, filepath.Join(localDir, "a.log"), []byte("a"))
+
+	if err = os.Remove(filepath.Join(localDir, "sub", "b.log")); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = client.Retry(m); err != nil || len(m.Failed()) != 0 {
+		t.Fatalf("retry: %+v, %v", m, err)
+	}
+	assertFile(t, filepath.Join(localDir, "a.log"), []byte("a"))
+	assertFile(t, filepath.Join(localDir, "sub/b.log"), []byte("b"))
+	if _, err := os.Stat(filepath.Join(localDir, "c.txt")); !os.IsNotExist(err) {
+		t.Errorf("c.txt was pulled: %v", err)
+	}
+}
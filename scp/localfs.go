@@ -0,0 +1,123 @@
+package scp
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrReadOnlyFS WithLocalFS 指定的文件系统不支持写入，无法作为拉取的目标
+var ErrReadOnlyFS = errors.New("local fs is read-only")
+
+// WriteFS 可写的本地文件系统，拉取文件时 WithLocalFS 指定的文件系统需要实现该接口
+type WriteFS interface {
+	fs.FS
+	// OpenFile 同 os.OpenFile
+	OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error)
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// WriteFile WriteFS 打开的可写文件
+type WriteFile interface {
+	io.Writer
+	io.Seeker
+	io.Closer
+}
+
+// DirFS 返回以 dir 为根目录的本地文件系统，与 os.DirFS 相同只接受 fs.ValidPath 格式的路径，同时支持写入
+func DirFS(dir string) WriteFS {
+	return osFS{root: dir}
+}
+
+// osFS 本地磁盘上的文件系统，root 为空时直接使用传入的本地路径，是未设置 WithLocalFS 时的默认值
+type osFS struct {
+	root string
+}
+
+func (f osFS) path(op, name string) (string, error) {
+	if f.root == "" {
+		return name, nil
+	}
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(f.root, filepath.FromSlash(name)), nil
+}
+
+func (f osFS) Open(name string) (fs.File, error) {
+	p, err := f.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (f osFS) OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error) {
+	p, err := f.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(p, flag, perm)
+}
+
+func (f osFS) MkdirAll(name string, perm fs.FileMode) error {
+	p, err := f.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, perm)
+}
+
+func (f osFS) Remove(name string) error {
+	p, err := f.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+func (f osFS) Chmod(name string, mode fs.FileMode) error {
+	p, err := f.path("chmod", name)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(p, mode)
+}
+
+func (f osFS) Chtimes(name string, atime, mtime time.Time) error {
+	p, err := f.path("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(p, atime, mtime)
+}
+
+// writeFS 返回拉取文件时使用的本地文件系统
+func (o *options) writeFS() (WriteFS, error) {
+	fsys, ok := o.localFS.(WriteFS)
+	if !ok {
+		return nil, ErrReadOnlyFS
+	}
+	return fsys, nil
+}
+
+// setFSMeta 设置本地文件的元数据，只有本地磁盘支持修改属主
+func setFSMeta(fsys WriteFS, name string, m fileMeta) error {
+	if f, ok := fsys.(osFS); ok {
+		p, err := f.path("chmod", name)
+		if err != nil {
+			return err
+		}
+		return setLocalMeta(p, m)
+	}
+	if err := fsys.Chmod(name, m.mode); err != nil {
+		return err
+	}
+	return fsys.Chtimes(name, m.atime, m.mtime)
+}
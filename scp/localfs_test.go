@@ -0,0 +1,218 @@
+package scp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// newMemClient 连接一个内存中的 sftp 服务，远端文件不落盘
+func newMemClient(t testing.TB, opts ...Option) *Client {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return &Client{Client: client, addr: "memory", opts: opts}
+}
+
+// memFS 内存中的可写文件系统
+type memFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: fstest.MapFS{}}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(name)
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[name]
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		f = &fstest.MapFile{Mode: perm, ModTime: time.Now()}
+		m.files[name] = f
+	case flag&os.O_TRUNC != 0:
+		f.Data = nil
+	}
+	return &memFile{fs: m, file: f}, nil
+}
+
+func (m *memFS) MkdirAll(name string, perm fs.FileMode) error {
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name].Mode = mode
+	return nil
+}
+
+func (m *memFS) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name].ModTime = mtime
+	return nil
+}
+
+func (m *memFS) data(name string) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.files[name]; ok {
+		return f.Data
+	}
+	return nil
+}
+
+type memFile struct {
+	fs     *memFS
+	file   *fstest.MapFile
+	offset int64
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if end := f.offset + int64(len(p)); end > int64(len(f.file.Data)) {
+		f.file.Data = append(f.file.Data, make([]byte, end-int64(len(f.file.Data)))...)
+	}
+	copy(f.file.Data[f.offset:], p)
+	f.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("unsupported whence")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Close() error { return nil }
+
+func TestScpFromFS(t *testing.T) {
+	client := newMemClient(t, WithoutProgress(), WithChunkSize(1000))
+	big := bytes.Repeat([]byte("0123456789"), 1000)
+	fsys := fstest.MapFS{
+		"etc/app.conf": {Data: []byte("port = 8080\n"), Mode: 0o600},
+		"bin/app":      {Data: big, Mode: 0o755},
+	}
+
+	m, err := client.ScpFiles([]FilePair{
+		{Local: "etc/app.conf", Remote: "/app.conf"},
+		{Local: "bin/app", Remote: "/app"},
+	}, WithLocalFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Failed()) != 0 || m.Entries[1].SHA256 == "" {
+		t.Fatalf("manifest: %+v", m.Entries)
+	}
+
+	got, err := client.DownloadBytes("/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "port = 8080\n" {
+		t.Fatalf("got %q", got)
+	}
+	if got, err = client.DownloadBytes("/app"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, big) {
+		t.Fatal("content mismatch")
+	}
+
+	if err = client.Scp("missing", "/missing", WithLocalFS(fsys)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestPullToFS(t *testing.T) {
+	client := newMemClient(t, WithoutProgress(), WithChunkSize(1000))
+	data := bytes.Repeat([]byte("abcdefghij"), 1000)
+	if err := client.UploadBytes(data, "/data.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := newMemFS()
+	if err := client.Pull("/data.bin", "data.bin", WithLocalFS(fsys)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fsys.data("data.bin"), data) {
+		t.Fatal("content mismatch")
+	}
+
+	// 拉取失败时不留下本地文件
+	if err := client.Pull("/missing", "missing", WithLocalFS(fsys)); err == nil {
+		t.Fatal("pull missing file succeeded")
+	}
+	if _, err := fs.Stat(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("stat err = %v, want fs.ErrNotExist", err)
+	}
+
+	if err := client.Pull("/data.bin", "data.bin", WithLocalFS(fstest.MapFS{})); !errors.Is(err, ErrReadOnlyFS) {
+		t.Fatalf("err = %v, want ErrReadOnlyFS", err)
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	client := newMemClient(t, WithoutProgress())
+	dir := t.TempDir()
+	fsys := DirFS(dir)
+	if err := client.UploadBytes([]byte("hello"), "/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Pull("/hello.txt", "sub/hello.txt", WithLocalFS(fsys)); err == nil {
+		t.Fatal("pull to missing directory succeeded")
+	}
+	if err := fsys.MkdirAll("sub", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Pull("/hello.txt", "sub/hello.txt", WithLocalFS(fsys)); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path.Join(dir, "sub/hello.txt"), []byte("hello"))
+
+	if err := client.Pull("/hello.txt", "../escape.txt", WithLocalFS(fsys)); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("err = %v, want fs.ErrInvalid", err)
+	}
+}
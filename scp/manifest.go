@@ -0,0 +1,142 @@
+package scp
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+type Direction string
+
+const (
+	DirectionUpload   Direction = "upload"   // 从本地推送到远端
+	DirectionDownload Direction = "download" // 从远端拉取到本地
+)
+
+// ManifestEntry 批量传输中单个文件的结果
+type ManifestEntry struct {
+	Local    string        `json:"local"`
+	Remote   string        `json:"remote"`
+	Size     int64         `json:"size"`
+	SHA256   string        `json:"sha256,omitempty"` // 传输成功时本地文件的 sha256，分段传输的文件在传输完成后重新读取本地文件计算
+	Duration time.Duration `json:"duration"`         // 单位 纳秒
+	Error    string        `json:"error,omitempty"`  // 为空表示传输成功
+}
+
+// Manifest 批量传输的清单，可以序列化为 JSON 保存，之后通过 Client.Retry 重试失败的文件
+type Manifest struct {
+	Direction Direction       `json:"direction"`
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+func newManifest(direction Direction, states []*transferFile, fsys fs.FS) *Manifest {
+	m := &Manifest{Direction: direction, Entries: make([]ManifestEntry, 0, len(states))}
+	for _, st := range states {
+		entry := ManifestEntry{
+			Local:  st.Local,
+			Remote: st.Remote,
+			Size:   st.size,
+		}
+		if !st.start.IsZero() && st.end.After(st.start) {
+			entry.Duration = st.end.Sub(st.start)
+		}
+		err := st.err
+		if err == nil && st.sum != nil {
+			entry.SHA256 = hex.EncodeToString(st.sum)
+		} else if err == nil {
+			entry.SHA256, err = fileSHA256(fsys, st.Local)
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		m.Entries = append(m.Entries, entry)
+	}
+	return m
+}
+
+// Failed 返回传输失败的条目
+func (m *Manifest) Failed() []ManifestEntry {
+	var failed []ManifestEntry
+	for _, entry := range m.Entries {
+		if entry.Error != "" {
+			failed = append(failed, entry)
+		}
+	}
+	return failed
+}
+
+// Save 将清单以 JSON 格式保存到本地文件
+func (m *Manifest) Save(name string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+// LoadManifest 从本地 JSON 文件加载清单
+func LoadManifest(name string) (*Manifest, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("load manifest %s err: %w", name, err)
+	}
+	return m, nil
+}
+
+// Retry 按清单的传输方向重新传输其中失败的文件，返回合并了本次结果的新清单
+func (c *Client) Retry(m *Manifest, opts ...Option) (*Manifest, error) {
+	return c.RetryContext(context.Background(), m, opts...)
+}
+
+// RetryContext 同 Retry，ctx 取消时中止传输
+func (c *Client) RetryContext(ctx context.Context, m *Manifest, opts ...Option) (*Manifest, error) {
+	var files []FilePair
+	var index []int
+	for i, entry := range m.Entries {
+		if entry.Error != "" {
+			files = append(files, FilePair{Local: entry.Local, Remote: entry.Remote})
+			index = append(index, i)
+		}
+	}
+
+	merged := &Manifest{Direction: m.Direction, Entries: append([]ManifestEntry(nil), m.Entries...)}
+	if len(files) == 0 {
+		return merged, nil
+	}
+
+	var retried *Manifest
+	var err error
+	switch m.Direction {
+	case DirectionUpload:
+		retried, err = c.ScpFilesContext(ctx, files, opts...)
+	case DirectionDownload:
+		retried, err = c.PullFilesContext(ctx, files, opts...)
+	default:
+		return nil, fmt.Errorf("retry manifest err: unknown direction %q", m.Direction)
+	}
+	for i, entry := range retried.Entries {
+		merged.Entries[index[i]] = entry
+	}
+	return merged, err
+}
+
+func fileSHA256(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum, err := checksum(f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
@@ -0,0 +1,116 @@
+package scp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifestRetry(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+
+	localDir, remoteDir := t.TempDir(), t.TempDir()
+	data := randomFile(t, filepath.Join(localDir, "a"), 1000)
+	randomFile(t, filepath.Join(localDir, "b"), 2000)
+	files := []FilePair{
+		{Local: filepath.Join(localDir, "a"), Remote: filepath.Join(remoteDir, "a")},
+		{Local: filepath.Join(localDir, "missing"), Remote: filepath.Join(remoteDir, "missing")},
+		{Local: filepath.Join(localDir, "b"), Remote: filepath.Join(remoteDir, "no-such-dir", "b")},
+	}
+
+	m, err := client.ScpFiles(files)
+	if err == nil {
+		t.Fatal("want error")
+	}
+	if len(m.Entries) != 3 || len(m.Failed()) != 2 {
+		t.Fatalf("manifest = %+v", m)
+	}
+	sum := sha256.Sum256(data)
+	if entry := m.Entries[0]; entry.Error != "" || entry.Size != 1000 || entry.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("entry = %+v", entry)
+	}
+	assertFile(t, filepath.Join(remoteDir, "a"), data)
+
+	name := filepath.Join(t.TempDir(), "manifest.json")
+	if err = m.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadManifest(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Failed()) != 2 || loaded.Direction != DirectionUpload {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+
+	// 修复失败原因后只重试失败的文件
+	randomFile(t, filepath.Join(localDir, "missing"), 10)
+	if err = os.Mkdir(filepath.Join(remoteDir, "no-such-dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	retried, err := client.Retry(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retried.Entries) != 3 || len(retried.Failed()) != 0 {
+		t.Fatalf("retried = %+v", retried)
+	}
+	if retried.Entries[0] != loaded.Entries[0] {
+		t.Errorf("successful entry changed: %+v", retried.Entries[0])
+	}
+	if _, err = os.Stat(filepath.Join(remoteDir, "no-such-dir", "b")); err != nil {
+		t.Error(err)
+	}
+}
+
+// countFS 记录 Open 的次数
+type countFS struct {
+	fs.FS
+	opens int32
+}
+
+func (c *countFS) Open(name string) (fs.File, error) {
+	atomic.AddInt32(&c.opens, 1)
+	return c.FS.Open(name)
+}
+
+func TestManifestSHA256(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+	remoteDir := t.TempDir()
+	data := []byte("manifest content")
+	sum := sha256.Sum256(data)
+
+	// 单个 chunk 的文件在传输过程中计算，不再重新读取本地文件
+	fsys := &countFS{FS: fstest.MapFS{"a.txt": {Data: data, Mode: 0o644}}}
+	m, err := client.ScpFiles([]FilePair{{Local: "a.txt", Remote: filepath.Join(remoteDir, "a.txt")}}, WithLocalFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Entries[0].SHA256 != hex.EncodeToString(sum[:]) || fsys.opens != 2 {
+		t.Fatalf("entry = %+v, opens = %d", m.Entries[0], fsys.opens)
+	}
+
+	// 分段传输的文件在传输完成后计算
+	localDir := t.TempDir()
+	big := randomFile(t, filepath.Join(localDir, "big"), 10000)
+	bigSum := sha256.Sum256(big)
+	for _, pull := range []bool{false, true} {
+		var m *Manifest
+		if pull {
+			m, err = client.PullFiles([]FilePair{{Local: filepath.Join(localDir, "pulled"), Remote: filepath.Join(remoteDir, "big")}}, WithChunkSize(4096))
+		} else {
+			m, err = client.ScpFiles([]FilePair{{Local: filepath.Join(localDir, "big"), Remote: filepath.Join(remoteDir, "big")}}, WithChunkSize(4096))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Entries[0].SHA256 != hex.EncodeToString(bigSum[:]) {
+			t.Fatalf("pull = %v: entry = %+v", pull, m.Entries[0])
+		}
+	}
+}
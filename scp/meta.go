@@ -0,0 +1,83 @@
+package scp
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// fileMeta 需要在两端之间保留的文件元数据
+type fileMeta struct {
+	mode  os.FileMode
+	atime time.Time
+	mtime time.Time
+	uid   int // -1 表示未知
+	gid   int // -1 表示未知
+}
+
+// localMeta 从本地文件信息中提取元数据
+func localMeta(fi os.FileInfo) fileMeta {
+	m := fileMeta{
+		mode:  fi.Mode().Perm(),
+		atime: fi.ModTime(),
+		mtime: fi.ModTime(),
+		uid:   -1,
+		gid:   -1,
+	}
+	statMeta(fi, &m)
+	return m
+}
+
+// remoteMeta 从 sftp 返回的文件信息中提取元数据
+func remoteMeta(fi os.FileInfo) fileMeta {
+	m := fileMeta{
+		mode:  fi.Mode().Perm(),
+		atime: fi.ModTime(),
+		mtime: fi.ModTime(),
+		uid:   -1,
+		gid:   -1,
+	}
+	if st, ok := fi.Sys().(*sftp.FileStat); ok {
+		m.atime = time.Unix(int64(st.Atime), 0)
+		m.uid = int(st.UID)
+		m.gid = int(st.GID)
+	}
+	return m
+}
+
+// setRemoteMeta 设置远端文件的元数据，没有权限修改属主时忽略
+func (c *Client) setRemoteMeta(remote string, m fileMeta) error {
+	if m.uid >= 0 && m.gid >= 0 {
+		if err := c.Chown(remote, m.uid, m.gid); err != nil && !isPermission(err) {
+			return err
+		}
+	}
+	if err := c.Chmod(remote, m.mode); err != nil {
+		return err
+	}
+	return c.Chtimes(remote, m.atime, m.mtime)
+}
+
+// setLocalMeta 设置本地文件的元数据，没有权限修改属主时忽略
+func setLocalMeta(local string, m fileMeta) error {
+	if m.uid >= 0 && m.gid >= 0 {
+		if err := os.Lchown(local, m.uid, m.gid); err != nil && !isPermission(err) {
+			return err
+		}
+	}
+	if err := os.Chmod(local, m.mode); err != nil {
+		return err
+	}
+	return os.Chtimes(local, m.atime, m.mtime)
+}
+
+// isPermission 判断是否为权限不足错误，sftp 会把 SSH_FX_PERMISSION_DENIED 转换为 os.ErrPermission
+func isPermission(err error) bool {
+	var se *sftp.StatusError
+	if errors.As(err, &se) {
+		return se.FxCode() == sftp.ErrSSHFxPermissionDenied
+	}
+	return errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,16 @@
+package scp
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statMeta 补充 os.FileInfo 中没有的 atime 和属主
+func statMeta(fi os.FileInfo, m *fileMeta) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		m.atime = time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+		m.uid = int(st.Uid)
+		m.gid = int(st.Gid)
+	}
+}
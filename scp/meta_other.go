@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package scp
+
+import "os"
+
+// statMeta 当前平台无法获取 atime 和属主，保持默认值
+func statMeta(fi os.FileInfo, m *fileMeta) {}
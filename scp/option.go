@@ -0,0 +1,307 @@
+package scp
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+type options struct {
+	preserve bool     // 保留权限、属主和时间戳
+	keepTime bool     // 保留权限和时间戳，不修改属主，Sync 使用
+	includes []string // 只处理匹配的文件
+	excludes []string // 跳过匹配的文件和目录
+	delete   bool     // 同步时删除目标端多余的文件
+	dryRun   bool     // 只返回计划执行的操作，不实际传输
+	checksum bool     // 使用 sha256 而不是 大小+修改时间 判断文件是否变化
+
+	concurrency  int   // 并发传输的文件或分段数
+	chunkSize    int64 // 超过该大小的文件分段并发传输
+	showProgress bool  // 是否显示进度条
+
+	progressFunc func(name string, written, total int64) // 自定义进度回调，设置后不显示进度条
+
+	limiter *limiter    // 传输限速
+	mode    os.FileMode // 上传内存内容时设置的远端文件权限
+
+	compression Compression // 打包传输时使用的压缩算法
+	unsafeLinks bool        // 解压时允许指向目录之外的符号链接
+
+	bufferSize int  // 单个缓冲区的大小
+	direct     bool // 远端之间复制时优先由源主机直接推送
+
+	modifiedAfter  time.Time // 只匹配在该时间之后修改的文件
+	modifiedBefore time.Time // 只匹配在该时间之前修改的文件
+	minSize        int64     // 只匹配不小于该大小的文件
+	maxSize        int64     // 只匹配不大于该大小的文件，<= 0 时不限制
+
+	backup bool // 覆盖远端文件前先备份
+
+	pollInterval time.Duration // Tail 轮询远端文件的间隔
+	fromStart    bool          // Tail 从文件开头而不是末尾开始读取
+
+	localFS fs.FS // 本地文件系统，默认为本地磁盘
+
+	concurrentRequests int // 单个文件并发的 sftp 请求数，只在 NewClient 时生效
+
+	encryptionKey []byte // 上传时加密、拉取时解密使用的 AES 密钥
+
+	hostConcurrency  int // 同时分发的主机数
+	canary           int // 优先依次分发的金丝雀主机数
+	failureThreshold int // 失败主机数达到该值后停止分发，<= 0 时不限制
+}
+
+type Option func(o *options)
+
+// WithPreserve 传输时保留文件的权限位、atime/mtime，有权限时同时保留 uid/gid
+func WithPreserve() Option {
+	return func(o *options) {
+		o.preserve = true
+	}
+}
+
+// WithInclude 只处理匹配任意一条规则的文件，规则语法见 match
+func WithInclude(patterns ...string) Option {
+	return func(o *options) {
+		o.includes = append(o.includes, patterns...)
+	}
+}
+
+// WithExclude 跳过匹配任意一条规则的文件和目录，优先级高于 WithInclude
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// WithDelete 同步时删除目标端存在但源端不存在的文件，被过滤规则跳过的文件不会被删除
+func WithDelete() Option {
+	return func(o *options) {
+		o.delete = true
+	}
+}
+
+// WithDryRun 只计算需要执行的操作，不修改目标端
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithChecksum 使用 sha256 判断文件是否变化，比 大小+修改时间 更准确，但需要读取两端的全部内容
+func WithChecksum() Option {
+	return func(o *options) {
+		o.checksum = true
+	}
+}
+
+// WithConcurrency 多文件和分段传输的最大并发数，默认 4
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.concurrency = n
+	}
+}
+
+// WithChunkSize 超过 size 字节的文件切分为多段并发传输，默认 32MB，<= 0 时不切分
+func WithChunkSize(size int64) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// WithoutProgress 不显示进度条
+func WithoutProgress() Option {
+	return func(o *options) {
+		o.showProgress = false
+	}
+}
+
+// WithProgressFunc 使用回调代替进度条，name 为传输的描述，多主机分发时为主机地址
+func WithProgressFunc(fn func(name string, written, total int64)) Option {
+	return func(o *options) {
+		o.progressFunc = fn
+	}
+}
+
+// WithRateLimit 限制传输速率，单位 字节/秒，<= 0 时不限速
+//
+//	在 NewClient 中设置时该客户端的所有传输共享同一个限速器，在单次调用中设置时只限制本次传输
+func WithRateLimit(bytesPerSec int64) Option {
+	return func(o *options) {
+		o.limiter = newLimiter(bytesPerSec)
+	}
+}
+
+// WithMode 通过 Upload 系列方法写入远端文件时设置文件权限，默认由远端决定
+func WithMode(perm os.FileMode) Option {
+	return func(o *options) {
+		o.mode = perm.Perm()
+	}
+}
+
+// WithCompression 打包传输时使用的压缩算法，默认不压缩
+func WithCompression(c Compression) Option {
+	return func(o *options) {
+		o.compression = c
+	}
+}
+
+// WithUnsafeLinks PullArchive 解压时允许绝对路径或指向目录之外的符号链接，只应用于可信的远端目录
+//
+//	即使设置了该选项，也不会经过符号链接写入文件
+func WithUnsafeLinks() Option {
+	return func(o *options) {
+		o.unsafeLinks = true
+	}
+}
+
+// WithBufferSize 流式传输和拉取文件时单个缓冲区的大小，默认 256KB
+//
+//	拉取时大于 32KB 的缓冲区会被拆分为多个并发的 sftp 读请求，大文件可以适当调大
+func WithBufferSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.bufferSize = size
+		}
+	}
+}
+
+// WithDirect 远端之间复制时，先尝试在源主机上执行 scp 直接推送到目标主机，失败后再经本地中转
+//
+//	源主机需要能够通过密钥免密登录目标主机
+func WithDirect() Option {
+	return func(o *options) {
+		o.direct = true
+	}
+}
+
+// WithModifiedAfter 查找远端文件时只匹配修改时间晚于 t 的文件
+func WithModifiedAfter(t time.Time) Option {
+	return func(o *options) {
+		o.modifiedAfter = t
+	}
+}
+
+// WithModifiedBefore 查找远端文件时只匹配修改时间早于 t 的文件
+func WithModifiedBefore(t time.Time) Option {
+	return func(o *options) {
+		o.modifiedBefore = t
+	}
+}
+
+// WithMinSize 查找远端文件时只匹配不小于 size 字节的文件
+func WithMinSize(size int64) Option {
+	return func(o *options) {
+		o.minSize = size
+	}
+}
+
+// WithMaxSize 查找远端文件时只匹配不大于 size 字节的文件
+func WithMaxSize(size int64) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
+
+// WithBackup 覆盖远端已存在的文件前先创建带时间戳的备份，见 Client.Backup
+func WithBackup() Option {
+	return func(o *options) {
+		o.backup = true
+	}
+}
+
+// WithPollInterval Tail 轮询远端文件的间隔，默认 1s
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WithFromStart Tail 从文件开头开始读取，默认只读取开始 Tail 之后新增的内容
+func WithFromStart() Option {
+	return func(o *options) {
+		o.fromStart = true
+	}
+}
+
+// WithConcurrentRequests 单个文件最多同时发出 n 个 sftp 读写请求，并开启并发写入，只在 NewClient 时生效
+//
+//	默认只有读取是并发的，高延迟链路上传大文件时开启可以显著提升速度
+func WithConcurrentRequests(n int) Option {
+	return func(o *options) {
+		o.concurrentRequests = n
+	}
+}
+
+// WithEncryptionKey 上传时在本地使用 AES-GCM 加密文件内容，远端只保存密文，拉取时使用同一个密钥解密
+//
+//	key 长度为 16、24 或 32 字节，分别对应 AES-128/192/256
+//	对 Scp/Pull/ScpFiles/PullFiles/Upload/Download/Sync 生效，加密后的文件只能整体顺序传输，不再分段并发和跳过空洞
+func WithEncryptionKey(key []byte) Option {
+	return func(o *options) {
+		o.encryptionKey = key
+	}
+}
+
+// WithLocalFS 本地文件从 fsys 读取或写入 fsys，本地路径使用 fsys 中的路径格式
+//
+//	eg: WithLocalFS(embedFS) 从 embed.FS 推送文件，WithLocalFS(DirFS("/data")) 限定在 /data 目录下读写
+//	拉取时 fsys 需要实现 WriteFS，对 Scp/Pull/ScpFiles/PullFiles/Retry/PullGlob/Sync/Distribute 生效
+func WithLocalFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.localFS = fsys
+	}
+}
+
+// WithHostConcurrency 多主机分发时同时分发的主机数，默认 5
+func WithHostConcurrency(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.hostConcurrency = n
+	}
+}
+
+// WithCanary 多主机分发时先依次分发前 n 台主机，全部成功后再并发分发其余主机
+func WithCanary(n int) Option {
+	return func(o *options) {
+		o.canary = n
+	}
+}
+
+// WithFailureThreshold 多主机分发时失败的主机数达到 n 后不再分发剩余的主机
+func WithFailureThreshold(n int) Option {
+	return func(o *options) {
+		o.failureThreshold = n
+	}
+}
+
+// options 合并客户端默认选项和本次调用的选项
+func (c *Client) options(opts []Option) *options {
+	o := &options{
+		concurrency:  4,
+		chunkSize:    32 << 20,
+		showProgress: true,
+		bufferSize:   256 << 10,
+		pollInterval: time.Second,
+		localFS:      osFS{},
+
+		hostConcurrency: 5,
+	}
+	for _, opt := range c.opts {
+		opt(o)
+	}
+	if c.limiter != nil {
+		o.limiter = c.limiter
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,95 @@
+package scp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// limiter 令牌桶限速器，允许的突发流量为一秒的速率
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒允许的字节数
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(bytesPerSec int64) *limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &limiter{
+		rate:   float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// wait 消耗 n 个令牌，令牌不足时等待补充，ctx 取消时立即返回
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	// 先预支令牌，并发的调用方依次排队
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ctxReader 每次读取前检查 ctx，并按 limiter 限速
+type ctxReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *limiter
+}
+
+func newCtxReader(ctx context.Context, r io.Reader, l *limiter) io.Reader {
+	return &ctxReader{ctx: ctx, r: r, limiter: l}
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if r.limiter != nil && len(p) > int(r.limiter.rate) {
+		// 单次读取不超过一秒的流量，避免长时间的突发和等待
+		p = p[:int(r.limiter.rate)]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 && r.limiter != nil {
+		if werr := r.limiter.wait(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// closeOnCancel ctx 取消时关闭 c，让阻塞在网络上的读写立即返回，调用 stop 停止监听
+func closeOnCancel(ctx context.Context, c io.Closer) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
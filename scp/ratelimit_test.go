@@ -0,0 +1,42 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(100 << 10)
+	r := newCtxReader(context.Background(), bytes.NewReader(make([]byte, 200<<10)), l)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 200<<10 {
+		t.Fatalf("copied %d bytes", n)
+	}
+	// 第一秒的令牌可以直接使用，剩余 100KB 需要约 1 秒
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("elapsed = %v, want about 1s", elapsed)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := newLimiter(1 << 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := io.Copy(io.Discard, newCtxReader(ctx, bytes.NewReader(make([]byte, 100<<10)), l))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancel took %v", elapsed)
+	}
+}
@@ -0,0 +1,197 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// ErrInsufficientSpace 远端磁盘可用空间不足
+var ErrInsufficientSpace = errors.New("insufficient disk space")
+
+// DiskUsage 远端文件系统的空间信息，单位 字节
+type DiskUsage struct {
+	Total     uint64 // 总空间
+	Free      uint64 // 剩余空间，包含只有 root 可用的保留空间
+	Available uint64 // 非 root 用户可用的空间
+}
+
+// MkdirAllMode 创建远端目录及不存在的父目录，新创建的目录权限设置为 perm，已存在的目录保持不变
+func (c *Client) MkdirAllMode(dir string, perm os.FileMode) error {
+	var missing []string
+	for p := path.Clean(dir); ; p = path.Dir(p) {
+		info, err := c.Stat(p)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("mkdir %s err: %s is not a directory", dir, p)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("mkdir %s err: %w", dir, err)
+		}
+		missing = append(missing, p)
+		if p == "/" || p == "." {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := c.Mkdir(missing[i]); err != nil {
+			// 并发创建时目录可能已经存在
+			if info, statErr := c.Stat(missing[i]); statErr != nil || !info.IsDir() {
+				return fmt.Errorf("mkdir %s err: %w", missing[i], err)
+			}
+			continue
+		}
+		if err := c.Chmod(missing[i], perm); err != nil {
+			return fmt.Errorf("chmod %s err: %w", missing[i], err)
+		}
+	}
+	return nil
+}
+
+// RemoveAll 递归删除远端文件或目录，路径不存在时返回 nil
+//
+//	与 sftp.Client.RemoveAll 不同，符号链接只删除链接本身，不会进入链接指向的目录
+func (c *Client) RemoveAll(p string) error {
+	info, err := c.Lstat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("remove %s err: %w", p, err)
+	}
+
+	if info.IsDir() {
+		entries, err := c.ReadDir(p)
+		if err != nil {
+			return fmt.Errorf("remove %s err: %w", p, err)
+		}
+		for _, entry := range entries {
+			if err = c.RemoveAll(path.Join(p, entry.Name())); err != nil {
+				return err
+			}
+		}
+		err = c.RemoveDirectory(p)
+	} else {
+		err = c.Remove(p)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove %s err: %w", p, err)
+	}
+	return nil
+}
+
+// EnsureFile 确保远端文件的内容为 content、权限为 perm，已经一致时不做修改，返回是否发生了修改
+//
+//	内容不同时先写入同目录下的临时文件再重命名覆盖，WithBackup 时覆盖前备份原文件
+//	WithEncryptionKey 时远端保存密文，解密后与 content 比较，无法解密时直接覆盖
+func (c *Client) EnsureFile(remote string, content []byte, perm os.FileMode, opts ...Option) (bool, error) {
+	return c.EnsureFileContext(context.Background(), remote, content, perm, opts...)
+}
+
+// EnsureFileContext 同 EnsureFile，ctx 取消时中止传输
+func (c *Client) EnsureFileContext(ctx context.Context, remote string, content []byte, perm os.FileMode, opts ...Option) (bool, error) {
+	o := c.options(opts)
+
+	info, err := c.Stat(remote)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, fmt.Errorf("ensure file %s err: %w", remote, err)
+	case info.IsDir():
+		return false, fmt.Errorf("ensure file %s err: is a directory", remote)
+	case info.Size() == storedSize(int64(len(content)), o):
+		current, err := c.DownloadBytesContext(ctx, remote, WithoutProgress(), WithEncryptionKey(o.encryptionKey))
+		if errors.Is(err, ErrDecrypt) {
+			// 远端不是用当前密钥加密的内容，直接覆盖
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("ensure file %s err: %w", remote, err)
+		}
+		if bytes.Equal(current, content) {
+			if info.Mode().Perm() == perm.Perm() {
+				return false, nil
+			}
+			if err = c.Chmod(remote, perm); err != nil {
+				return false, fmt.Errorf("ensure file %s err: %w", remote, err)
+			}
+			return true, nil
+		}
+	}
+
+	if err == nil && o.backup {
+		if _, err = c.BackupContext(ctx, remote); err != nil {
+			return false, err
+		}
+	}
+
+	tmp := path.Join(path.Dir(remote), fmt.Sprintf(".%s.%d.tmp", path.Base(remote), time.Now().UnixNano()))
+	if err = c.UploadBytesContext(ctx, content, tmp, WithoutProgress(), WithMode(perm), WithEncryptionKey(o.encryptionKey)); err != nil {
+		return false, fmt.Errorf("ensure file %s err: %w", remote, err)
+	}
+	if err = c.replace(tmp, remote); err != nil {
+		c.Remove(tmp)
+		return false, fmt.Errorf("ensure file %s err: %w", remote, err)
+	}
+	return true, nil
+}
+
+// Backup 将远端文件复制为同目录下带时间戳的备份文件，保留原文件的元数据，返回备份文件路径
+//
+//	eg: /etc/app.conf => /etc/app.conf.20230102150405.bak
+func (c *Client) Backup(remote string) (string, error) {
+	return c.BackupContext(context.Background(), remote)
+}
+
+// BackupContext 同 Backup，ctx 取消时中止复制
+func (c *Client) BackupContext(ctx context.Context, remote string) (string, error) {
+	backup := fmt.Sprintf("%s.%s.bak", remote, time.Now().Format("20060102150405"))
+	if err := CopyContext(ctx, c, remote, c, backup, WithoutProgress(), WithPreserve()); err != nil {
+		return "", fmt.Errorf("backup %s err: %w", remote, err)
+	}
+	return backup, nil
+}
+
+// DiskUsage 返回远端路径所在文件系统的空间信息，需要服务端支持 statvfs@openssh.com 扩展
+func (c *Client) DiskUsage(remote string) (*DiskUsage, error) {
+	st, err := c.StatVFS(remote)
+	if err != nil {
+		return nil, fmt.Errorf("statvfs %s err: %w", remote, err)
+	}
+	return &DiskUsage{
+		Total:     st.Blocks * st.Frsize,
+		Free:      st.Bfree * st.Frsize,
+		Available: st.Bavail * st.Frsize,
+	}, nil
+}
+
+// CheckDiskSpace 检查远端路径所在文件系统是否至少有 need 字节的可用空间，不足时返回 ErrInsufficientSpace
+func (c *Client) CheckDiskSpace(remote string, need uint64) error {
+	usage, err := c.DiskUsage(remote)
+	if err != nil {
+		return err
+	}
+	if usage.Available < need {
+		return fmt.Errorf("check disk space %s err: %w: need %d bytes, available %d bytes",
+			remote, ErrInsufficientSpace, need, usage.Available)
+	}
+	return nil
+}
+
+// replace 用 src 覆盖 dst，优先使用原子的 posix-rename 扩展
+func (c *Client) replace(src, dst string) error {
+	if _, ok := c.HasExtension("posix-rename@openssh.com"); ok {
+		return c.PosixRename(src, dst)
+	}
+	if err := c.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return c.Rename(src, dst)
+}
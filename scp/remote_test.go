@@ -0,0 +1,137 @@
+package scp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMkdirAllMode(t *testing.T) {
+	client := newTestClient(t)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b", "c")
+	if err := client.MkdirAllMode(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a", "a/b", "a/b/c"} {
+		info, err := os.Stat(filepath.Join(root, p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o700 {
+			t.Errorf("%s mode = %v", p, info.Mode().Perm())
+		}
+	}
+	// 重复调用不报错
+	if err := client.MkdirAllMode(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTree(t, root, map[string]string{"file": "x"})
+	if err := client.MkdirAllMode(filepath.Join(root, "file", "sub"), 0o755); err == nil {
+		t.Error("want error when parent is a file")
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	client := newTestClient(t)
+
+	root, outside := t.TempDir(), t.TempDir()
+	writeTree(t, root, map[string]string{"dir/a": "a", "dir/sub/b": "b"})
+	writeTree(t, outside, map[string]string{"keep": "keep"})
+	if err := os.Symlink(outside, filepath.Join(root, "dir", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.RemoveAll(filepath.Join(root, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dir")); !os.IsNotExist(err) {
+		t.Errorf("dir was not removed: %v", err)
+	}
+	assertFile(t, filepath.Join(outside, "keep"), []byte("keep"))
+
+	if err := client.RemoveAll(filepath.Join(root, "dir")); err != nil {
+		t.Errorf("remove missing path: %v", err)
+	}
+}
+
+func TestEnsureFile(t *testing.T) {
+	client := newTestClient(t)
+
+	remote := filepath.Join(t.TempDir(), "app.conf")
+	changed, err := client.EnsureFile(remote, []byte("v1"), 0o640)
+	if err != nil || !changed {
+		t.Fatalf("create: changed = %v, err = %v", changed, err)
+	}
+	changed, err = client.EnsureFile(remote, []byte("v1"), 0o640)
+	if err != nil || changed {
+		t.Fatalf("unchanged: changed = %v, err = %v", changed, err)
+	}
+	changed, err = client.EnsureFile(remote, []byte("v1"), 0o600)
+	if err != nil || !changed {
+		t.Fatalf("chmod: changed = %v, err = %v", changed, err)
+	}
+
+	changed, err = client.EnsureFile(remote, []byte("v2"), 0o600, WithBackup())
+	if err != nil || !changed {
+		t.Fatalf("update: changed = %v, err = %v", changed, err)
+	}
+	assertFile(t, remote, []byte("v2"))
+	backups, err := filepath.Glob(remote + ".*.bak")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("backups = %v, err = %v", backups, err)
+	}
+	assertFile(t, backups[0], []byte("v1"))
+
+	entries, err := os.ReadDir(filepath.Dir(remote))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+}
+
+func TestEnsureFileEncrypted(t *testing.T) {
+	// 内存中的 sftp 服务不支持修改权限，固定为 0644
+	client := newMemClient(t, WithoutProgress(), WithEncryptionKey(bytes.Repeat([]byte{7}, 32)))
+
+	changed, err := client.EnsureFile("/app.conf", []byte("v1"), 0o644)
+	if err != nil || !changed {
+		t.Fatalf("create: changed = %v, err = %v", changed, err)
+	}
+	changed, err = client.EnsureFile("/app.conf", []byte("v1"), 0o644)
+	if err != nil || changed {
+		t.Fatalf("unchanged: changed = %v, err = %v", changed, err)
+	}
+	changed, err = client.EnsureFile("/app.conf", []byte("v2"), 0o644)
+	if err != nil || !changed {
+		t.Fatalf("update: changed = %v, err = %v", changed, err)
+	}
+	if got, err := client.DownloadBytes("/app.conf"); err != nil || string(got) != "v2" {
+		t.Fatalf("content = %q, err = %v", got, err)
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	client := newTestClient(t)
+
+	dir := t.TempDir()
+	usage, err := client.DiskUsage(dir)
+	if err != nil {
+		t.Skipf("statvfs not supported: %v", err)
+	}
+	if usage.Total == 0 || usage.Available > usage.Total {
+		t.Errorf("usage = %+v", usage)
+	}
+	if err = client.CheckDiskSpace(dir, 1); err != nil {
+		t.Error(err)
+	}
+	if err = client.CheckDiskSpace(dir, usage.Total+1); !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("err = %v, want %v", err, ErrInsufficientSpace)
+	}
+}
@@ -1,20 +1,23 @@
 package scp
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"os"
 
 	"github.com/pkg/sftp"
-	"github.com/schollz/progressbar/v3"
 	"golang.org/x/crypto/ssh"
 )
 
 type Client struct {
 	*sftp.Client
+	conn    *ssh.Client // 底层 ssh 连接，用于执行远端命令
+	addr    string      // 远端地址 host:port
+	user    string      // 登录用户
+	opts    []Option    // 客户端级别的默认选项，每次传输前应用
+	limiter *limiter    // 客户端级别的限速器，所有传输共享
 }
 
-func NewClient(addr, username, passwd string) (*Client, error) {
+func NewClient(addr, username, passwd string, opts ...Option) (*Client, error) {
 	config := &ssh.ClientConfig{
 		User: username,
 		Auth: []ssh.AuthMethod{
@@ -26,70 +29,51 @@ func NewClient(addr, username, passwd string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("ssh dial %s err: %s", addr, err.Error())
 	}
-	client, err := sftp.NewClient(cli)
-	if err != nil {
+	c := &Client{conn: cli, addr: addr, user: username, opts: opts}
+	o := c.options(nil)
+	var sftpOpts []sftp.ClientOption
+	if o.concurrentRequests > 0 {
+		sftpOpts = append(sftpOpts, sftp.MaxConcurrentRequestsPerFile(o.concurrentRequests), sftp.UseConcurrentWrites(true))
+	}
+	if c.Client, err = sftp.NewClient(cli, sftpOpts...); err != nil {
+		cli.Close()
 		return nil, err
 	}
-	return &Client{client}, nil
+	c.limiter = o.limiter
+	return c, nil
 }
 
-// Scp 从本地推送到远端
-func (c *Client) Scp(local, remote string) error {
-	localInfo, err := os.Stat(local)
-	if err != nil {
-		return err
+// Close 关闭 sftp 会话和底层 ssh 连接
+func (c *Client) Close() error {
+	err := c.Client.Close()
+	if c.conn != nil {
+		if connErr := c.conn.Close(); err == nil {
+			err = connErr
+		}
 	}
-	localFile, err := os.Open(local)
-	if err != nil {
-		return err
-	}
-	defer localFile.Close()
-
-	remoteFile, err := c.Create(remote)
-	if err != nil {
-		return err
-	}
-	defer remoteFile.Close()
+	return err
+}
 
-	bar := progressbar.DefaultBytes(
-		localInfo.Size(),
-		fmt.Sprintf("scp %s to %s", local, remote),
-	)
+// Scp 从本地推送到远端
+func (c *Client) Scp(local, remote string, opts ...Option) error {
+	return c.ScpContext(context.Background(), local, remote, opts...)
+}
 
-	_, err = io.Copy(io.MultiWriter(remoteFile, bar), localFile)
-	if err != nil {
-		return err
-	}
-	return nil
+// ScpContext 同 Scp，ctx 取消时中止传输并删除不完整的远端文件
+func (c *Client) ScpContext(ctx context.Context, local, remote string, opts ...Option) error {
+	o := c.options(opts)
+	states := c.uploadFiles(ctx, []FilePair{{Local: local, Remote: remote}}, o, fmt.Sprintf("scp %s to %s", local, remote))
+	return states[0].err
 }
 
 // Pull 从远端拉取到本地
-func (c *Client) Pull(remote, local string) error {
-	remoteFile, err := c.Open(remote)
-	if err != nil {
-		return err
-	}
-	defer remoteFile.Close()
-
-	fileInfo, err := remoteFile.Stat()
-	if err != nil {
-		return err
-	}
-
-	localFile, err := os.OpenFile(local, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
-	if err != nil {
-		return err
-	}
-	defer localFile.Close()
-
-	bar := progressbar.DefaultBytes(
-		fileInfo.Size(),
-		fmt.Sprintf("pull %s to %s", remote, local),
-	)
+func (c *Client) Pull(remote, local string, opts ...Option) error {
+	return c.PullContext(context.Background(), remote, local, opts...)
+}
 
-	_, err = io.Copy(io.MultiWriter(localFile, bar), remoteFile)
-	if err != nil {
-		return err
-	}
-	return nil
+// PullContext 同 Pull，ctx 取消时中止传输并删除不完整的本地文件
+func (c *Client) PullContext(ctx context.Context, remote, local string, opts ...Option) error {
+	o := c.options(opts)
+	states := c.downloadFiles(ctx, []FilePair{{Local: local, Remote: remote}}, o, fmt.Sprintf("pull %s to %s", remote, local))
+	return states[0].err
 }
@@ -1,7 +1,12 @@
 package scp
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestScp(t *testing.T) {
@@ -32,3 +37,85 @@ func TestPull(t *testing.T) {
 		return
 	}
 }
+
+func TestScpPreserve(t *testing.T) {
+	client := newTestClient(t)
+
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.txt")
+	remote := filepath.Join(dir, "remote.txt")
+	if err := os.WriteFile(local, []byte("preserve"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(local, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Scp(local, remote, WithPreserve()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
+
+func TestPullPreserve(t *testing.T) {
+	client := newTestClient(t)
+
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.txt")
+	local := filepath.Join(dir, "local.txt")
+	if err := os.WriteFile(remote, []byte("preserve"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.Local)
+	if err := os.Chtimes(remote, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Pull(remote, local, WithPreserve()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
+
+func TestScpContextCancel(t *testing.T) {
+	client := newTestClient(t, WithoutProgress(), WithRateLimit(64<<10))
+
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.bin")
+	remote := filepath.Join(dir, "remote.bin")
+	randomFile(t, local, 1<<20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.ScpContext(ctx, local, remote)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancel took %v", elapsed)
+	}
+	if _, err := os.Stat(remote); !os.IsNotExist(err) {
+		t.Errorf("partial remote file was not removed: %v", err)
+	}
+}
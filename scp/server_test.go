@@ -0,0 +1,142 @@
+package scp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"os/exec"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUser   = "test"
+	testPasswd = "test"
+)
+
+// newTestServer 启动一个本地 ssh 服务，支持 sftp 子系统和 exec，测试结束后自动关闭
+func newTestServer(t testing.TB) string {
+	t.Helper()
+	return newTestServerDir(t, "")
+}
+
+// newTestServerDir 同 newTestServer，远端的相对路径和命令的工作目录为 dir，用于模拟多台主机
+func newTestServerDir(t testing.TB, dir string) string {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, passwd []byte) (*ssh.Permissions, error) {
+			if conn.User() == testUser && string(passwd) == testPasswd {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config, dir)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// newTestClient 连接 newTestServer 启动的服务
+func newTestClient(t testing.TB, opts ...Option) *Client {
+	t.Helper()
+
+	client, err := NewClient(newTestServer(t), testUser, testPasswd, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func serveConn(conn net.Conn, config *ssh.ServerConfig, dir string) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go serveSession(ch, chReqs, dir)
+	}
+}
+
+func serveSession(ch ssh.Channel, reqs <-chan *ssh.Request, dir string) {
+	defer ch.Close()
+
+	for req := range reqs {
+		switch req.Type {
+		case "subsystem":
+			if string(req.Payload[4:]) != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			var serverOpts []sftp.ServerOption
+			if dir != "" {
+				serverOpts = append(serverOpts, sftp.WithServerWorkingDirectory(dir))
+			}
+			server, err := sftp.NewServer(ch, serverOpts...)
+			if err != nil {
+				return
+			}
+			server.Serve()
+			return
+		case "exec":
+			req.Reply(true, nil)
+			cmd := exec.Command("sh", "-c", string(req.Payload[4:]))
+			cmd.Dir = dir
+			cmd.Stdin = ch
+			cmd.Stdout = ch
+			cmd.Stderr = ch.Stderr()
+
+			status := make([]byte, 4)
+			if err := cmd.Run(); err != nil {
+				code := 1
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					code = exitErr.ExitCode()
+				}
+				binary.BigEndian.PutUint32(status, uint32(code))
+			}
+			ch.SendRequest("exit-status", false, status)
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
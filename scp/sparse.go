@@ -0,0 +1,16 @@
+package scp
+
+// extent 文件中一段连续的数据区间
+type extent struct {
+	offset int64
+	length int64
+}
+
+// dataSize 返回所有数据区间的总长度
+func dataSize(extents []extent) int64 {
+	var n int64
+	for _, e := range extents {
+		n += e.length
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package scp
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const (
+	seekData = 3 // SEEK_DATA
+	seekHole = 4 // SEEK_HOLE
+)
+
+// dataExtents 通过 SEEK_DATA/SEEK_HOLE 找出文件中的数据区间，跳过稀疏文件的空洞
+//
+//	文件系统不支持时返回整个文件
+func dataExtents(f *os.File, size int64) ([]extent, error) {
+	extents := []extent{}
+	for off := int64(0); off < size; {
+		start, err := f.Seek(off, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			// off 之后全部是空洞
+			break
+		}
+		if errors.Is(err, syscall.EINVAL) {
+			return []extent{{length: size}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		end, err := f.Seek(start, seekHole)
+		if err != nil {
+			return nil, err
+		}
+		if end > size {
+			end = size
+		}
+		if end > start {
+			extents = append(extents, extent{offset: start, length: end - start})
+		}
+		off = end
+	}
+	return extents, nil
+}
@@ -0,0 +1,88 @@
+package scp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// sparseFile 创建一个大小为 size、只在 offsets 处有数据的稀疏文件，返回完整内容
+func sparseFile(t testing.TB, name string, size int64, offsets ...int64) []byte {
+	t.Helper()
+
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, size)
+	for _, off := range offsets {
+		data := bytes.Repeat([]byte{0xab}, 4096)
+		if _, err = f.WriteAt(data, off); err != nil {
+			t.Fatal(err)
+		}
+		copy(want[off:], data)
+	}
+	return want
+}
+
+func TestDataExtents(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sparse.img")
+	const size = 8 << 20
+	sparseFile(t, name, size, 0, 4<<20)
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	extents, err := dataExtents(f, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataSize(extents) == size {
+		t.Skip("filesystem does not support SEEK_HOLE")
+	}
+	if len(extents) != 2 || extents[0].offset != 0 || extents[1].offset > 4<<20 {
+		t.Fatalf("extents = %+v", extents)
+	}
+}
+
+func TestScpSparse(t *testing.T) {
+	client := newTestClient(t, WithoutProgress(), WithConcurrentRequests(16), WithChunkSize(1<<20))
+	dir := t.TempDir()
+	local := filepath.Join(dir, "sparse.img")
+	const size = 16 << 20
+	want := sparseFile(t, local, size, 1<<20, 9<<20)
+
+	remote := filepath.Join(dir, "remote.img")
+	if err := client.Scp(local, remote); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, remote, want)
+
+	localStat, remoteStat := new(syscall.Stat_t), new(syscall.Stat_t)
+	if err := syscall.Stat(local, localStat); err != nil {
+		t.Fatal(err)
+	}
+	if localStat.Blocks*512 >= size {
+		t.Skip("filesystem does not support sparse files")
+	}
+	if err := syscall.Stat(remote, remoteStat); err != nil {
+		t.Fatal(err)
+	}
+	if remoteStat.Blocks*512 >= size {
+		t.Fatalf("remote file allocated %d bytes, want sparse", remoteStat.Blocks*512)
+	}
+
+	pulled := filepath.Join(dir, "pulled.img")
+	if err := client.Pull(remote, pulled, WithBufferSize(4<<20)); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, pulled, want)
+}
@@ -0,0 +1,129 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// Upload 将 r 中的内容写入远端文件，size 为内容长度，仅用于显示进度，未知时传 -1
+//
+//	出错时删除不完整的远端文件，WithMode 可以设置远端文件权限
+func (c *Client) Upload(r io.Reader, size int64, remote string, opts ...Option) error {
+	return c.UploadContext(context.Background(), r, size, remote, opts...)
+}
+
+// UploadContext 同 Upload，ctx 取消时中止传输并删除不完整的远端文件
+func (c *Client) UploadContext(ctx context.Context, r io.Reader, size int64, remote string, opts ...Option) (err error) {
+	o := c.options(opts)
+
+	remoteFile, err := c.Create(remote)
+	if err != nil {
+		return err
+	}
+	defer remoteFile.Close()
+	defer func() {
+		if err != nil {
+			c.Remove(remote)
+		}
+	}()
+	defer closeOnCancel(ctx, remoteFile)()
+
+	bar := o.progress(size, fmt.Sprintf("upload to %s", remote))
+	src := io.TeeReader(newCtxReader(ctx, r, o.limiter), bar)
+	if o.encryptionKey != nil {
+		if src, err = newEncryptReader(src, o.encryptionKey); err != nil {
+			return err
+		}
+	}
+	if _, err = io.Copy(remoteFile, src); err != nil {
+		return fmt.Errorf("upload to %s err: %w", remote, cause(ctx, err))
+	}
+	if err = remoteFile.Close(); err != nil {
+		return err
+	}
+	if o.mode != 0 {
+		return c.Chmod(remote, o.mode)
+	}
+	return nil
+}
+
+// UploadBytes 将内存中的内容写入远端文件
+func (c *Client) UploadBytes(data []byte, remote string, opts ...Option) error {
+	return c.UploadBytesContext(context.Background(), data, remote, opts...)
+}
+
+// UploadBytesContext 同 UploadBytes，ctx 取消时中止传输
+func (c *Client) UploadBytesContext(ctx context.Context, data []byte, remote string, opts ...Option) error {
+	return c.UploadContext(ctx, bytes.NewReader(data), int64(len(data)), remote, opts...)
+}
+
+// UploadTemplate 使用 data 渲染 text/template 模板 tmpl，并将结果写入远端文件
+func (c *Client) UploadTemplate(tmpl string, data interface{}, remote string, opts ...Option) error {
+	return c.UploadTemplateContext(context.Background(), tmpl, data, remote, opts...)
+}
+
+// UploadTemplateContext 同 UploadTemplate，ctx 取消时中止传输
+func (c *Client) UploadTemplateContext(ctx context.Context, tmpl string, data interface{}, remote string, opts ...Option) error {
+	t, err := template.New(remote).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parse template for %s err: %w", remote, err)
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return fmt.Errorf("render template for %s err: %w", remote, err)
+	}
+	return c.UploadBytesContext(ctx, buf.Bytes(), remote, opts...)
+}
+
+// Download 将远端文件的内容写入 w，返回写入的字节数
+func (c *Client) Download(remote string, w io.Writer, opts ...Option) (int64, error) {
+	return c.DownloadContext(context.Background(), remote, w, opts...)
+}
+
+// DownloadContext 同 Download，ctx 取消时中止传输
+func (c *Client) DownloadContext(ctx context.Context, remote string, w io.Writer, opts ...Option) (int64, error) {
+	o := c.options(opts)
+
+	remoteFile, err := c.Open(remote)
+	if err != nil {
+		return 0, err
+	}
+	defer remoteFile.Close()
+	defer closeOnCancel(ctx, remoteFile)()
+
+	info, err := remoteFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// 进度按远端文件的大小计算，加密时为密文的大小
+	bar := o.progress(info.Size(), fmt.Sprintf("download %s", remote))
+	src := io.TeeReader(newCtxReader(ctx, remoteFile, o.limiter), bar)
+	if o.encryptionKey != nil {
+		if src, err = newDecryptReader(src, o.encryptionKey); err != nil {
+			return 0, err
+		}
+	}
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return n, fmt.Errorf("download %s err: %w", remote, cause(ctx, err))
+	}
+	return n, nil
+}
+
+// DownloadBytes 读取远端文件的全部内容
+func (c *Client) DownloadBytes(remote string, opts ...Option) ([]byte, error) {
+	return c.DownloadBytesContext(context.Background(), remote, opts...)
+}
+
+// DownloadBytesContext 同 DownloadBytes，ctx 取消时中止传输
+func (c *Client) DownloadBytesContext(ctx context.Context, remote string, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := c.DownloadContext(ctx, remote, &buf, opts...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,72 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadDownload(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+	ctx := context.Background()
+	remote := filepath.Join(t.TempDir(), "app.conf")
+
+	if err := client.UploadContext(ctx, strings.NewReader("from reader"), -1, remote, WithMode(0o600)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	var buf bytes.Buffer
+	n, err := client.DownloadContext(ctx, remote, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len("from reader")) || buf.String() != "from reader" {
+		t.Errorf("Download = %d, %q", n, buf.String())
+	}
+
+	if err = client.UploadBytesContext(ctx, []byte("from bytes"), remote); err != nil {
+		t.Fatal(err)
+	}
+	data, err := client.DownloadBytesContext(ctx, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "from bytes" {
+		t.Errorf("DownloadBytes = %q", data)
+	}
+}
+
+func TestUploadTemplate(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+	ctx := context.Background()
+	remote := filepath.Join(t.TempDir(), "app.conf")
+
+	tmpl := "listen {{.Port}}\n{{range .Hosts}}upstream {{.}}\n{{end}}"
+	data := map[string]interface{}{
+		"Port":  8080,
+		"Hosts": []string{"10.0.0.1", "10.0.0.2"},
+	}
+	if err := client.UploadTemplateContext(ctx, tmpl, data, remote); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, remote, []byte("listen 8080\nupstream 10.0.0.1\nupstream 10.0.0.2\n"))
+
+	// 缺少字段时报错，且不会留下远端文件
+	other := filepath.Join(filepath.Dir(remote), "other.conf")
+	if err := client.UploadTemplateContext(ctx, "{{.Missing}}", map[string]interface{}{}, other); err == nil {
+		t.Error("want error for missing key")
+	}
+	if _, err := os.Stat(other); !os.IsNotExist(err) {
+		t.Errorf("other.conf exists: %v", err)
+	}
+}
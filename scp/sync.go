@@ -0,0 +1,295 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type SyncOp string
+
+const (
+	SyncMkdir  SyncOp = "mkdir"  // 创建远端目录
+	SyncCreate SyncOp = "create" // 上传远端不存在的文件
+	SyncUpdate SyncOp = "update" // 覆盖远端已变化的文件
+	SyncDelete SyncOp = "delete" // 删除远端多余的文件或目录
+)
+
+// SyncAction 同步过程中的一次操作
+type SyncAction struct {
+	Op   SyncOp
+	Path string // 相对于同步目录的路径，使用 / 分隔
+	Size int64  // 上传的文件大小，其他操作为 0
+}
+
+func (a SyncAction) String() string {
+	return fmt.Sprintf("%s %s", a.Op, a.Path)
+}
+
+// Sync 将本地目录同步到远端，只传输新增和变化的文件，返回执行（或 WithDryRun 时计划执行）的操作
+//
+//	默认通过 大小+修改时间 判断变化，WithChecksum 改为比较 sha256
+//	上传的文件总是保留权限位和修改时间，保证下一次同步可以正确比较，属主只在 WithPreserve 时保留
+//	WithDelete 删除远端多余的文件，WithInclude/WithExclude 过滤文件
+//	WithEncryptionKey 时远端保存密文，按密文的大小和修改时间判断变化，不能与 WithChecksum 同时使用
+//	同时返回上传文件的清单，部分文件上传失败时可以用于 Retry，没有上传文件或 WithDryRun 时为 nil
+func (c *Client) Sync(localDir, remoteDir string, opts ...Option) ([]SyncAction, *Manifest, error) {
+	return c.SyncContext(context.Background(), localDir, remoteDir, opts...)
+}
+
+// SyncContext 同 Sync，ctx 取消时中止同步
+func (c *Client) SyncContext(ctx context.Context, localDir, remoteDir string, opts ...Option) ([]SyncAction, *Manifest, error) {
+	o := c.options(opts)
+	if o.checksum && o.encryptionKey != nil {
+		return nil, nil, fmt.Errorf("sync %s to %s err: checksum can not be used with encryption key", localDir, remoteDir)
+	}
+
+	localFiles, err := walkLocal(localDir, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	remoteFiles, partial, err := c.walkRemote(remoteDir, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actions, err := c.planSync(localDir, remoteDir, localFiles, remoteFiles, partial, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.dryRun {
+		return actions, nil, nil
+	}
+
+	// 先创建目录，再并发上传文件，最后删除多余的文件
+	var files []FilePair
+	for _, action := range actions {
+		local := filepath.Join(localDir, filepath.FromSlash(action.Path))
+		remote := path.Join(remoteDir, action.Path)
+
+		switch action.Op {
+		case SyncMkdir:
+			err = c.MkdirAll(remote)
+			if err == nil {
+				err = c.Chmod(remote, localFiles[action.Path].Mode().Perm())
+			}
+			if err != nil {
+				return actions, nil, fmt.Errorf("sync %s err: %w", action, err)
+			}
+		case SyncCreate, SyncUpdate:
+			files = append(files, FilePair{Local: local, Remote: remote})
+		}
+	}
+
+	var m *Manifest
+	if len(files) > 0 {
+		o.keepTime = true
+		states := c.uploadFiles(ctx, files, o, fmt.Sprintf("sync %s to %s", localDir, remoteDir))
+		m = newManifest(DirectionUpload, states, o.localFS)
+		if err = batchErr(states); err != nil {
+			return actions, m, fmt.Errorf("sync %s to %s err: %w", localDir, remoteDir, err)
+		}
+	}
+
+	for _, action := range actions {
+		if action.Op != SyncDelete {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return actions, m, err
+		}
+		if err = c.RemoveAll(path.Join(remoteDir, action.Path)); err != nil {
+			return actions, m, fmt.Errorf("sync %s err: %w", action, err)
+		}
+	}
+	return actions, m, nil
+}
+
+// walkLocal 遍历本地目录，返回相对路径到文件信息的映射，根目录的相对路径为 "."
+func walkLocal(root string, o *options) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := fs.WalkDir(o.localFS, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip, err := skipEntry(rel, info, o); skip {
+			return err
+		}
+		files[rel] = info
+		return nil
+	})
+	return files, err
+}
+
+// walkRemote 遍历远端目录，目录不存在时返回空映射
+//
+//	同时返回包含被过滤条目的目录（以及它们的上级目录），这些目录不能整体删除
+func (c *Client) walkRemote(root string, o *options) (map[string]os.FileInfo, map[string]bool, error) {
+	files := make(map[string]os.FileInfo)
+	partial := make(map[string]bool)
+	root = path.Clean(root)
+	if _, err := c.Stat(root); errors.Is(err, os.ErrNotExist) {
+		return files, partial, nil
+	}
+
+	walker := c.Walk(root)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return nil, nil, err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), root), "/")
+		if rel == "" {
+			rel = "."
+		}
+		info := walker.Stat()
+		if skip, _ := skipEntry(rel, info, o); skip {
+			if info.IsDir() {
+				walker.SkipDir()
+			}
+			for dir := path.Dir(rel); !partial[dir]; dir = path.Dir(dir) {
+				partial[dir] = true
+				if dir == "." {
+					break
+				}
+			}
+			continue
+		}
+		files[rel] = info
+	}
+	return files, partial, nil
+}
+
+// skipEntry 判断遍历到的条目是否需要跳过，被排除的目录返回 filepath.SkipDir
+func skipEntry(rel string, info os.FileInfo, o *options) (bool, error) {
+	if rel == "." {
+		return false, nil
+	}
+	if info.IsDir() {
+		if o.excluded(rel) {
+			return true, filepath.SkipDir
+		}
+		return false, nil
+	}
+	return !info.Mode().IsRegular() || !o.included(rel), nil
+}
+
+// planSync 比较两端的文件，生成按执行顺序排列的操作
+func (c *Client) planSync(localDir, remoteDir string, localFiles, remoteFiles map[string]os.FileInfo, partial map[string]bool, o *options) ([]SyncAction, error) {
+	var actions []SyncAction
+
+	for _, rel := range sortedKeys(localFiles) {
+		local := localFiles[rel]
+		remote, exists := remoteFiles[rel]
+		if exists && remote.IsDir() != local.IsDir() {
+			return nil, fmt.Errorf("sync %s err: type mismatch between local and remote", rel)
+		}
+
+		if local.IsDir() {
+			if !exists {
+				actions = append(actions, SyncAction{Op: SyncMkdir, Path: rel})
+			}
+			continue
+		}
+		if !exists {
+			actions = append(actions, SyncAction{Op: SyncCreate, Path: rel, Size: local.Size()})
+			continue
+		}
+		changed, err := c.changed(filepath.Join(localDir, filepath.FromSlash(rel)), path.Join(remoteDir, rel), local, remote, o)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			actions = append(actions, SyncAction{Op: SyncUpdate, Path: rel, Size: local.Size()})
+		}
+	}
+
+	if o.delete {
+		// 目录会被整体删除，其下的条目不再单独删除
+		// 包含被过滤条目的目录不删除，只删除其中匹配的条目，删除后目录也不会为空
+		var deleted []string
+	next:
+		for _, rel := range sortedKeys(remoteFiles) {
+			if _, ok := localFiles[rel]; ok {
+				continue
+			}
+			for _, dir := range deleted {
+				if strings.HasPrefix(rel, dir+"/") {
+					continue next
+				}
+			}
+			if partial[rel] {
+				continue
+			}
+			actions = append(actions, SyncAction{Op: SyncDelete, Path: rel})
+			deleted = append(deleted, rel)
+		}
+	}
+	return actions, nil
+}
+
+// changed 判断文件内容是否发生变化
+func (c *Client) changed(local, remote string, localInfo, remoteInfo os.FileInfo, o *options) (bool, error) {
+	if storedSize(localInfo.Size(), o) != remoteInfo.Size() {
+		return true, nil
+	}
+	if !o.checksum {
+		// sftp 只支持秒级时间戳
+		return localInfo.ModTime().Unix() != remoteInfo.ModTime().Unix(), nil
+	}
+
+	localFile, err := o.localFS.Open(local)
+	if err != nil {
+		return false, err
+	}
+	defer localFile.Close()
+	localSum, err := checksum(localFile)
+	if err != nil {
+		return false, err
+	}
+
+	remoteFile, err := c.Open(remote)
+	if err != nil {
+		return false, err
+	}
+	defer remoteFile.Close()
+	remoteSum, err := checksum(remoteFile)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(localSum, remoteSum), nil
+}
+
+func checksum(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func sortedKeys(m map[string]os.FileInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package scp
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestSyncKeepsOwner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("chown requires root")
+	}
+	client := newTestClient(t)
+
+	localDir := t.TempDir()
+	writeTree(t, localDir, map[string]string{"a.conf": "a"})
+	if err := os.Chown(filepath.Join(localDir, "a.conf"), 12345, 12345); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := func(dir string) uint32 {
+		t.Helper()
+		info, err := os.Stat(filepath.Join(dir, "a.conf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Sys().(*syscall.Stat_t).Uid
+	}
+
+	// 默认不修改远端文件的属主
+	remoteDir := t.TempDir()
+	if _, _, err := client.Sync(localDir, remoteDir); err != nil {
+		t.Fatal(err)
+	}
+	if got := uid(remoteDir); got != 0 {
+		t.Fatalf("uid = %d, want 0", got)
+	}
+
+	remoteDir = t.TempDir()
+	if _, _, err := client.Sync(localDir, remoteDir, WithPreserve()); err != nil {
+		t.Fatal(err)
+	}
+	if got := uid(remoteDir); got != 12345 {
+		t.Fatalf("uid with WithPreserve = %d, want 12345", got)
+	}
+}
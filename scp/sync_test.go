@@ -0,0 +1,207 @@
+package scp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.log", "a.log", true},
+		{"*.log", "a/b/c.log", true},
+		{"*.log", "a.txt", false},
+		{"conf/*.yaml", "conf/app.yaml", true},
+		{"conf/*.yaml", "conf/sub/app.yaml", false},
+		{"conf/**", "conf/sub/app.yaml", true},
+		{"**/*.json", "a.json", true},
+		{"**/*.json", "a/b/c.json", true},
+		{"a/**/c", "a/c", true},
+		{"a/**/c", "a/b/d/c", true},
+		{"a/**/c", "a/b/d", false},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	client := newTestClient(t)
+
+	localDir := t.TempDir()
+	remoteDir := filepath.Join(t.TempDir(), "dst")
+	writeTree(t, localDir, map[string]string{
+		"a.conf":       "a",
+		"sub/b.conf":   "b",
+		"sub/c.tmp":    "c",
+		"cache/d.conf": "d",
+	})
+
+	actions, m, err := client.Sync(localDir, remoteDir, WithExclude("*.tmp", "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 2 || len(m.Failed()) != 0 || m.Direction != DirectionUpload {
+		t.Fatalf("manifest = %+v", m)
+	}
+	want := []SyncAction{
+		{Op: SyncMkdir, Path: "."},
+		{Op: SyncCreate, Path: "a.conf", Size: 1},
+		{Op: SyncMkdir, Path: "sub"},
+		{Op: SyncCreate, Path: "sub/b.conf", Size: 1},
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	if _, err := os.Stat(filepath.Join(remoteDir, "sub", "c.tmp")); !os.IsNotExist(err) {
+		t.Errorf("excluded file was uploaded: %v", err)
+	}
+
+	// 没有变化时不做任何操作
+	actions, m, err = client.Sync(localDir, remoteDir, WithExclude("*.tmp", "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 || m != nil {
+		t.Fatalf("actions = %v, want none", actions)
+	}
+
+	// 修改和删除文件，dry run 不修改远端
+	writeTree(t, localDir, map[string]string{"a.conf": "changed"})
+	if err := os.Remove(filepath.Join(localDir, "sub", "b.conf")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, remoteDir, map[string]string{"extra/e.conf": "e"})
+	actions, _, err = client.Sync(localDir, remoteDir, WithExclude("*.tmp", "cache"), WithDelete(), WithDryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []SyncAction{
+		{Op: SyncUpdate, Path: "a.conf", Size: 7},
+		{Op: SyncDelete, Path: "extra"},
+		{Op: SyncDelete, Path: "sub/b.conf"},
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	if _, err := os.Stat(filepath.Join(remoteDir, "extra")); err != nil {
+		t.Errorf("dry run modified remote: %v", err)
+	}
+
+	if _, _, err = client.Sync(localDir, remoteDir, WithExclude("*.tmp", "cache"), WithDelete()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(remoteDir, "a.conf"))
+	if err != nil || string(data) != "changed" {
+		t.Errorf("a.conf = %q, %v", data, err)
+	}
+	for _, name := range []string{"extra", "sub/b.conf"} {
+		if _, err := os.Stat(filepath.Join(remoteDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not deleted: %v", name, err)
+		}
+	}
+
+	// 远端独有的目录中有被过滤的文件时，只删除其中匹配的文件
+	writeTree(t, remoteDir, map[string]string{"top.bin": "t", "extra/keep.bin": "k", "extra/gone.conf": "g", "extra/sub/gone.conf": "g"})
+	actions, _, err = client.Sync(localDir, remoteDir, WithExclude("*.tmp", "cache"), WithInclude("*.conf"), WithDelete())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []SyncAction{
+		{Op: SyncDelete, Path: "extra/gone.conf"},
+		{Op: SyncDelete, Path: "extra/sub"},
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	for _, name := range []string{"top.bin", "extra/keep.bin"} {
+		if _, err := os.Stat(filepath.Join(remoteDir, name)); err != nil {
+			t.Errorf("filtered %s was deleted: %v", name, err)
+		}
+	}
+}
+
+func TestSyncChecksum(t *testing.T) {
+	client := newTestClient(t)
+
+	localDir := t.TempDir()
+	remoteDir := t.TempDir()
+	writeTree(t, localDir, map[string]string{"a.conf": "aaa"})
+	writeTree(t, remoteDir, map[string]string{"a.conf": "bbb"})
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, dir := range []string{localDir, remoteDir} {
+		if err := os.Chtimes(filepath.Join(dir, "a.conf"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actions, _, err := client.Sync(localDir, remoteDir, WithDryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Fatalf("size+mtime actions = %v, want none", actions)
+	}
+	actions, _, err = client.Sync(localDir, remoteDir, WithDryRun(), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SyncAction{{Op: SyncUpdate, Path: "a.conf", Size: 3}}
+	if !reflect.DeepEqual(actions, want) {
+		t.Fatalf("checksum actions = %v, want %v", actions, want)
+	}
+}
+
+func TestSyncEncrypted(t *testing.T) {
+	client := newTestClient(t)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	localDir := t.TempDir()
+	remoteDir := filepath.Join(t.TempDir(), "dst")
+	writeTree(t, localDir, map[string]string{"a.conf": "aaa", "sub/b.conf": "bbb"})
+
+	actions, _, err := client.Sync(localDir, remoteDir, WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 4 {
+		t.Fatalf("first sync actions = %v", actions)
+	}
+	if data, err := os.ReadFile(filepath.Join(remoteDir, "a.conf")); err != nil || bytes.Contains(data, []byte("aaa")) {
+		t.Fatalf("remote a.conf = %q, %v", data, err)
+	}
+
+	actions, _, err = client.Sync(localDir, remoteDir, WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Fatalf("second sync actions = %v, want none", actions)
+	}
+
+	if _, _, err = client.Sync(localDir, remoteDir, WithEncryptionKey(key), WithChecksum()); err == nil {
+		t.Fatal("want error for checksum with encryption key")
+	}
+}
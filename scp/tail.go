@@ -0,0 +1,218 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// tailProbeSize 判断文件是否被轮转时比较的开头字节数
+const tailProbeSize = 512
+
+// Tailer 持续读取远端文件中新增的行
+type Tailer struct {
+	// Lines 新增的行，不包含换行符，停止后关闭
+	Lines <-chan string
+
+	err  error
+	done chan struct{}
+}
+
+// Err 返回导致 Tailer 停止的错误，ctx 取消时为 nil，需要在 Lines 关闭后调用
+func (t *Tailer) Err() error {
+	<-t.done
+	return t.err
+}
+
+// Tail 通过 sftp 轮询远端文件，类似 tail -F，直到读取出错，需要主动停止时使用 TailContext
+//
+//	文件变小时视为被截断，从头开始读取
+//	文件被轮转(移走后重新创建)时读完旧文件剩余的内容后切换到新文件
+//	sftp 不提供 inode，通过文件大小和开头内容判断是否发生了轮转
+//	WithPollInterval 设置轮询间隔，WithFromStart 从文件开头开始读取
+func (c *Client) Tail(remote string, opts ...Option) (*Tailer, error) {
+	return c.TailContext(context.Background(), remote, opts...)
+}
+
+// TailContext 同 Tail，ctx 取消时停止读取并关闭 Lines
+func (c *Client) TailContext(ctx context.Context, remote string, opts ...Option) (*Tailer, error) {
+	o := c.options(opts)
+
+	f, err := c.Open(remote)
+	if err != nil {
+		return nil, err
+	}
+	var offset int64
+	if !o.fromStart {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		offset = info.Size()
+	}
+
+	lines := make(chan string)
+	t := &Tailer{Lines: lines, done: make(chan struct{})}
+	tf := &tailFile{c: c, path: remote, f: f, offset: offset, lines: lines}
+	go func() {
+		defer close(t.done)
+		defer close(lines)
+		defer func() { tf.f.Close() }()
+		t.err = tf.run(ctx, o.pollInterval)
+	}()
+	return t, nil
+}
+
+type tailFile struct {
+	c       *Client
+	path    string
+	f       *sftp.File
+	offset  int64
+	partial []byte // 尚未遇到换行符的内容
+	lines   chan<- string
+}
+
+func (t *tailFile) run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := t.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *tailFile) poll(ctx context.Context) error {
+	info, err := t.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < t.offset {
+		// 文件被截断
+		t.offset, t.partial = 0, nil
+	}
+	if err = t.read(ctx); err != nil {
+		return err
+	}
+
+	rotated, err := t.rotated(info)
+	if err != nil || !rotated {
+		return err
+	}
+	f, err := t.c.Open(t.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	// 旧文件的内容已经读完，剩余不完整的行直接输出
+	if len(t.partial) > 0 {
+		if err = t.emit(ctx, string(t.partial)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	t.f.Close()
+	t.f, t.offset, t.partial = f, 0, nil
+	return t.read(ctx)
+}
+
+// read 读取 offset 之后的全部内容并按行输出
+func (t *tailFile) read(ctx context.Context) error {
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := t.f.ReadAt(buf, t.offset)
+		if n > 0 {
+			t.offset += int64(n)
+			t.partial = append(t.partial, buf[:n]...)
+			for {
+				i := bytes.IndexByte(t.partial, '\n')
+				if i < 0 {
+					break
+				}
+				line := strings.TrimSuffix(string(t.partial[:i]), "\r")
+				t.partial = t.partial[i+1:]
+				if err := t.emit(ctx, line); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF || (err == nil && n < len(buf)) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rotated 判断路径当前指向的文件是否已经不是正在读取的文件
+func (t *tailFile) rotated(current os.FileInfo) (bool, error) {
+	info, err := t.c.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// 旧文件已移走，新文件还没有创建
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < t.offset {
+		return true, nil
+	}
+	if info.Size() == current.Size() && info.ModTime().Equal(current.ModTime()) {
+		return false, nil
+	}
+
+	// 大小或修改时间不同，可能是同一个文件在写入，比较文件开头的内容
+	f, err := t.c.Open(t.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+	a, err := readProbe(f)
+	if err != nil {
+		return false, err
+	}
+	b, err := readProbe(t.f)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.HasPrefix(b, a) && !bytes.HasPrefix(a, b), nil
+}
+
+func readProbe(f *sftp.File) ([]byte, error) {
+	buf := make([]byte, tailProbeSize)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (t *tailFile) emit(ctx context.Context, line string) error {
+	select {
+	case t.lines <- line:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package scp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, name, content string) {
+	t.Helper()
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectLines(t *testing.T, tailer *Tailer, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got, ok := <-tailer.Lines:
+			if !ok {
+				t.Fatalf("lines closed, err = %v", tailer.Err())
+			}
+			if got != w {
+				t.Fatalf("line = %q, want %q", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %q", w)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	client := newTestClient(t)
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, name, "old line\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tailer, err := client.TailContext(ctx, name, WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appendFile(t, name, "line 1\nline")
+	appendFile(t, name, " 2\r\n")
+	expectLines(t, tailer, "line 1", "line 2")
+
+	// 截断
+	if err = os.Truncate(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, name, "after truncate\n")
+	expectLines(t, tailer, "after truncate")
+
+	// 轮转
+	appendFile(t, name, "before rotate\n")
+	expectLines(t, tailer, "before rotate")
+	if err = os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, name+".1", "tail of old\n")
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, name, "new file\n")
+	expectLines(t, tailer, "tail of old", "new file")
+
+	cancel()
+	for range tailer.Lines {
+	}
+	if err = tailer.Err(); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTailFromStart(t *testing.T) {
+	client := newTestClient(t)
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, name, "first\nsecond\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tailer, err := client.TailContext(ctx, name, WithFromStart(), WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectLines(t, tailer, "first", "second")
+}
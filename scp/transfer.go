@@ -0,0 +1,456 @@
+package scp
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/sync/errgroup"
+)
+
+// FilePair 一次文件传输的本地路径和远端路径
+type FilePair struct {
+	Local  string
+	Remote string
+}
+
+// chunk 一个文件中需要传输的一段区间，小文件只有一个 chunk
+type chunk struct {
+	file   *transferFile
+	offset int64
+	length int64
+	whole  bool // 整个文件只有这一个 chunk，传输时同时计算 sha256
+}
+
+// transferFile 批量传输中单个文件的状态
+type transferFile struct {
+	FilePair
+	meta    fileMeta
+	size    int64
+	created bool   // 目标文件已创建，失败时需要清理
+	sum     []byte // 传输过程中计算的本地内容的 sha256，分段传输时为 nil
+
+	mu    sync.Mutex
+	left  int // 剩余未完成的 chunk 数
+	err   error
+	start time.Time
+	end   time.Time
+}
+
+// begin 开始传输一个 chunk，文件已经失败时返回 false
+func (f *transferFile) begin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return false
+	}
+	if f.start.IsZero() {
+		f.start = time.Now()
+	}
+	return true
+}
+
+// finish 记录一个 chunk 的传输结果，只保留第一个错误
+func (f *transferFile) finish(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		if f.err == nil {
+			f.err = err
+		}
+		return
+	}
+	f.left--
+	f.end = time.Now()
+}
+
+// ScpFiles 并发推送多个文件，并发数由 WithConcurrency 控制，大文件按 WithChunkSize 分段并发写入
+//
+//	单个文件失败不影响其他文件，返回的清单记录了每个文件的结果，可以通过 Retry 重试失败的文件
+func (c *Client) ScpFiles(files []FilePair, opts ...Option) (*Manifest, error) {
+	return c.ScpFilesContext(context.Background(), files, opts...)
+}
+
+// ScpFilesContext 同 ScpFiles，ctx 取消时中止传输并删除未传输完成的远端文件
+func (c *Client) ScpFilesContext(ctx context.Context, files []FilePair, opts ...Option) (*Manifest, error) {
+	o := c.options(opts)
+	states := c.uploadFiles(ctx, files, o, fmt.Sprintf("scp %d files", len(files)))
+	return newManifest(DirectionUpload, states, o.localFS), batchErr(states)
+}
+
+// PullFiles 并发拉取多个文件，并发数由 WithConcurrency 控制，大文件按 WithChunkSize 分段并发读取
+//
+//	单个文件失败不影响其他文件，返回的清单记录了每个文件的结果，可以通过 Retry 重试失败的文件
+func (c *Client) PullFiles(files []FilePair, opts ...Option) (*Manifest, error) {
+	return c.PullFilesContext(context.Background(), files, opts...)
+}
+
+// PullFilesContext 同 PullFiles，ctx 取消时中止传输并删除未传输完成的本地文件
+func (c *Client) PullFilesContext(ctx context.Context, files []FilePair, opts ...Option) (*Manifest, error) {
+	o := c.options(opts)
+	states := c.downloadFiles(ctx, files, o, fmt.Sprintf("pull %d files", len(files)))
+	return newManifest(DirectionDownload, states, o.localFS), batchErr(states)
+}
+
+func (c *Client) uploadFiles(ctx context.Context, files []FilePair, o *options, desc string) []*transferFile {
+	states := make([]*transferFile, len(files))
+	var chunks []chunk
+	var total int64
+	for i, file := range files {
+		st := &transferFile{FilePair: file}
+		states[i] = st
+
+		info, extents, err := statLocal(o.localFS, file.Local)
+		if err != nil {
+			st.err = err
+			continue
+		}
+		st.meta, st.size = localMeta(info), info.Size()
+		if o.encryptionKey != nil {
+			// 密文只能从头顺序生成
+			extents = nil
+		}
+
+		// 先创建并清空远端文件，各个 chunk 只负责写入自己的区间
+		remoteFile, err := c.Create(file.Remote)
+		if err != nil {
+			st.err = err
+			continue
+		}
+		st.created = true
+		if extents != nil && dataSize(extents) < st.size {
+			// 空洞不会被写入，先截断到文件大小，远端同样生成稀疏文件
+			st.err = remoteFile.Truncate(st.size)
+		}
+		if err = remoteFile.Close(); st.err == nil {
+			st.err = err
+		}
+		if st.err != nil {
+			continue
+		}
+		fileChunks := splitChunks(st, extents, o.chunkSize)
+		st.left = len(fileChunks)
+		chunks = append(chunks, fileChunks...)
+		for _, ch := range fileChunks {
+			total += ch.length
+		}
+	}
+
+	bar := o.progress(total, desc)
+	runChunks(ctx, chunks, o.concurrency, func(ctx context.Context, ch chunk) {
+		if ch.file.begin() {
+			ch.file.finish(c.uploadChunk(ctx, ch, bar, o))
+		}
+	})
+
+	for _, st := range states {
+		if st.err == nil && st.left > 0 {
+			st.err = cause(ctx, errors.New("transfer not finished"))
+		}
+		if st.err != nil {
+			// 删除未完整传输的远端文件
+			if st.created {
+				c.Remove(st.Remote)
+			}
+			continue
+		}
+		if o.preserve {
+			st.err = c.setRemoteMeta(st.Remote, st.meta)
+		} else if o.keepTime {
+			if st.err = c.Chmod(st.Remote, st.meta.mode); st.err == nil {
+				st.err = c.Chtimes(st.Remote, st.meta.atime, st.meta.mtime)
+			}
+		}
+	}
+	return states
+}
+
+func (c *Client) downloadFiles(ctx context.Context, files []FilePair, o *options, desc string) []*transferFile {
+	states := make([]*transferFile, len(files))
+	fsys, fsErr := o.writeFS()
+	var chunks []chunk
+	var total int64
+	for i, file := range files {
+		st := &transferFile{FilePair: file}
+		states[i] = st
+		if fsErr != nil {
+			st.err = fsErr
+			continue
+		}
+
+		info, err := c.Stat(file.Remote)
+		if err != nil {
+			st.err = err
+			continue
+		}
+		st.meta, st.size = remoteMeta(info), info.Size()
+
+		localFile, err := fsys.OpenFile(file.Local, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			st.err = err
+			continue
+		}
+		st.created = true
+		if st.err = localFile.Close(); st.err != nil {
+			continue
+		}
+		var extents []extent
+		if o.encryptionKey == nil {
+			// 加密的文件只能从头顺序解密
+			extents = []extent{{length: st.size}}
+		}
+		fileChunks := splitChunks(st, extents, o.chunkSize)
+		st.left = len(fileChunks)
+		chunks = append(chunks, fileChunks...)
+		total += st.size
+	}
+
+	bar := o.progress(total, desc)
+	runChunks(ctx, chunks, o.concurrency, func(ctx context.Context, ch chunk) {
+		if ch.file.begin() {
+			ch.file.finish(c.downloadChunk(ctx, ch, bar, fsys, o))
+		}
+	})
+
+	for _, st := range states {
+		if st.err == nil && st.left > 0 {
+			st.err = cause(ctx, errors.New("transfer not finished"))
+		}
+		if st.err != nil {
+			// 删除未完整传输的本地文件
+			if st.created {
+				fsys.Remove(st.Local)
+			}
+			continue
+		}
+		if o.preserve {
+			st.err = setFSMeta(fsys, st.Local, st.meta)
+		}
+	}
+	return states
+}
+
+func (c *Client) uploadChunk(ctx context.Context, ch chunk, bar io.Writer, o *options) error {
+	localFile, err := o.localFS.Open(ch.file.Local)
+	if err != nil {
+		return err
+	}
+	defer localFile.Close()
+
+	remoteFile, err := c.OpenFile(ch.file.Remote, os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	defer remoteFile.Close()
+	defer closeOnCancel(ctx, remoteFile)()
+
+	if _, err = remoteFile.Seek(ch.offset, io.SeekStart); err != nil {
+		return err
+	}
+	// 不支持随机读取的文件只有一个从头开始的 chunk
+	var src io.Reader = localFile
+	if ra, ok := localFile.(io.ReaderAt); ok {
+		src = io.NewSectionReader(ra, ch.offset, ch.length)
+	}
+	// 提供 Size 让 sftp 在 WithConcurrentRequests 时并发写入
+	r := sizedReader{io.TeeReader(newCtxReader(ctx, src, o.limiter), bar), ch.length}
+	h := sha256.New()
+	if ch.whole {
+		r.Reader = io.TeeReader(r.Reader, h)
+	}
+	if o.encryptionKey != nil {
+		if r.Reader, err = newEncryptReader(r.Reader, o.encryptionKey); err != nil {
+			return err
+		}
+		r.size = encryptedSize(ch.length)
+	}
+	if _, err = io.Copy(remoteFile, r); err != nil {
+		return fmt.Errorf("scp %s to %s err: %w", ch.file.Local, ch.file.Remote, cause(ctx, err))
+	}
+	if err = remoteFile.Close(); err != nil {
+		return err
+	}
+	if ch.whole {
+		ch.file.sum = h.Sum(nil)
+	}
+	return nil
+}
+
+func (c *Client) downloadChunk(ctx context.Context, ch chunk, bar io.Writer, fsys WriteFS, o *options) error {
+	remoteFile, err := c.Open(ch.file.Remote)
+	if err != nil {
+		return err
+	}
+	defer remoteFile.Close()
+	defer closeOnCancel(ctx, remoteFile)()
+
+	localFile, err := fsys.OpenFile(ch.file.Local, os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer localFile.Close()
+
+	if _, err = localFile.Seek(ch.offset, io.SeekStart); err != nil {
+		return err
+	}
+	// 较大的缓冲区会被 sftp 拆分为多个并发的读请求，隐藏 ReadFrom 保证使用该缓冲区
+	r := io.TeeReader(newCtxReader(ctx, io.NewSectionReader(remoteFile, ch.offset, ch.length), o.limiter), bar)
+	if o.encryptionKey != nil {
+		if r, err = newDecryptReader(r, o.encryptionKey); err != nil {
+			return err
+		}
+	}
+	h := sha256.New()
+	if ch.whole {
+		r = io.TeeReader(r, h)
+	}
+	if _, err = io.CopyBuffer(struct{ io.Writer }{localFile}, r, make([]byte, o.bufferSize)); err != nil {
+		return fmt.Errorf("pull %s to %s err: %w", ch.file.Remote, ch.file.Local, cause(ctx, err))
+	}
+	if err = localFile.Close(); err != nil {
+		return err
+	}
+	if ch.whole {
+		ch.file.sum = h.Sum(nil)
+	}
+	return nil
+}
+
+// statLocal 返回本地文件的信息和需要传输的数据区间
+//
+//	本地磁盘上的稀疏文件只返回数据区间，extents 为 nil 表示文件不支持随机读取，只能整体顺序传输
+func statLocal(fsys fs.FS, name string) (info fs.FileInfo, extents []extent, err error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if info, err = f.Stat(); err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+
+	switch f := f.(type) {
+	case *os.File:
+		extents, err = dataExtents(f, info.Size())
+	case io.ReaderAt:
+		extents = []extent{{length: info.Size()}}
+	}
+	return info, extents, err
+}
+
+// sizedReader 提供剩余长度的 reader
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+func (r sizedReader) Size() int64 { return r.size }
+
+// cause ctx 取消后关闭连接导致的错误统一返回 ctx.Err()
+func cause(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// splitChunks 按 chunkSize 切分文件的各个数据区间，chunkSize <= 0 时每个区间一个 chunk，extents 为 nil 时整个文件一个 chunk
+func splitChunks(file *transferFile, extents []extent, chunkSize int64) []chunk {
+	if extents == nil {
+		return []chunk{{file: file, length: file.size, whole: true}}
+	}
+	var chunks []chunk
+	for _, e := range extents {
+		if chunkSize <= 0 || e.length <= chunkSize {
+			chunks = append(chunks, chunk{file: file, offset: e.offset, length: e.length})
+			continue
+		}
+		for off := int64(0); off < e.length; off += chunkSize {
+			length := chunkSize
+			if off+length > e.length {
+				length = e.length - off
+			}
+			chunks = append(chunks, chunk{file: file, offset: e.offset + off, length: length})
+		}
+	}
+	if len(chunks) == 1 && chunks[0].offset == 0 && chunks[0].length == file.size {
+		chunks[0].whole = true
+	}
+	return chunks
+}
+
+// runChunks 最多 concurrency 个并发处理所有 chunk，ctx 取消后不再启动新的 chunk
+func runChunks(ctx context.Context, chunks []chunk, concurrency int, fn func(context.Context, chunk)) {
+	g := new(errgroup.Group)
+	g.SetLimit(concurrency)
+	for _, ch := range chunks {
+		ch := ch
+		if ctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			if ctx.Err() == nil {
+				fn(ctx, ch)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+}
+
+// batchErr 汇总批量传输中失败的文件，全部成功时返回 nil
+func batchErr(states []*transferFile) error {
+	var first error
+	failed := 0
+	for _, st := range states {
+		if st.err != nil {
+			if first == nil {
+				first = st.err
+			}
+			failed++
+		}
+	}
+	if failed <= 1 || len(states) == 1 {
+		return first
+	}
+	return fmt.Errorf("%d of %d files failed, first err: %w", failed, len(states), first)
+}
+
+// progress 创建进度条，设置了 WithProgressFunc 时使用回调，WithoutProgress 时丢弃进度
+func (o *options) progress(total int64, desc string) io.Writer {
+	if o.progressFunc != nil {
+		return &progressFunc{fn: o.progressFunc, name: desc, total: total}
+	}
+	if !o.showProgress {
+		return io.Discard
+	}
+	return progressbar.DefaultBytes(total, desc)
+}
+
+// progressFunc 将写入的字节数累加后通知回调，可以并发写入
+type progressFunc struct {
+	fn    func(name string, written, total int64)
+	name  string
+	total int64
+
+	mu      sync.Mutex
+	written int64
+}
+
+func (p *progressFunc) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written += int64(len(b))
+	p.fn(p.name, p.written, p.total)
+	return len(b), nil
+}
@@ -0,0 +1,99 @@
+package scp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func randomFile(t testing.TB, name string, size int) []byte {
+	t.Helper()
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func assertFile(t testing.TB, name string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: content mismatch, got %d bytes, want %d bytes", name, len(got), len(want))
+	}
+}
+
+func TestScpFiles(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+
+	localDir, remoteDir := t.TempDir(), t.TempDir()
+	var files []FilePair
+	contents := make(map[string][]byte)
+	for i, size := range []int{0, 100, 4096, 100000} {
+		name := fmt.Sprintf("f%d", i)
+		contents[name] = randomFile(t, filepath.Join(localDir, name), size)
+		files = append(files, FilePair{Local: filepath.Join(localDir, name), Remote: filepath.Join(remoteDir, name)})
+	}
+
+	if _, err := client.ScpFiles(files, WithConcurrency(3), WithChunkSize(1000)); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range contents {
+		assertFile(t, filepath.Join(remoteDir, name), data)
+	}
+}
+
+func TestPullFiles(t *testing.T) {
+	client := newTestClient(t, WithoutProgress())
+
+	localDir, remoteDir := t.TempDir(), t.TempDir()
+	var files []FilePair
+	contents := make(map[string][]byte)
+	for i, size := range []int{0, 100, 4096, 100000} {
+		name := fmt.Sprintf("f%d", i)
+		contents[name] = randomFile(t, filepath.Join(remoteDir, name), size)
+		files = append(files, FilePair{Local: filepath.Join(localDir, name), Remote: filepath.Join(remoteDir, name)})
+	}
+	// 已存在的更大的本地文件需要被截断
+	randomFile(t, filepath.Join(localDir, "f1"), 5000)
+
+	if _, err := client.PullFiles(files, WithConcurrency(3), WithChunkSize(1000)); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range contents {
+		assertFile(t, filepath.Join(localDir, name), data)
+	}
+}
+
+func BenchmarkScpFiles(b *testing.B) {
+	for _, concurrency := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			client := newTestClient(b, WithoutProgress())
+
+			localDir, remoteDir := b.TempDir(), b.TempDir()
+			var files []FilePair
+			for i := 0; i < 16; i++ {
+				name := fmt.Sprintf("f%d", i)
+				randomFile(b, filepath.Join(localDir, name), 1<<20)
+				files = append(files, FilePair{Local: filepath.Join(localDir, name), Remote: filepath.Join(remoteDir, name)})
+			}
+
+			b.SetBytes(16 << 20)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.ScpFiles(files, WithConcurrency(concurrency), WithChunkSize(256<<10)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}